@@ -3,42 +3,101 @@ package apiserver
 import (
 	"context"
 	"errors"
+	"fmt"
+	"sync/atomic"
 
+	"github.com/prometheus/client_golang/prometheus"
 	"golang.org/x/sync/errgroup"
 
 	"github.com/DoomLordor/logger"
 
+	"github.com/DoomLordor/go-apiserver/capture"
+	"github.com/DoomLordor/go-apiserver/compression"
 	"github.com/DoomLordor/go-apiserver/debug"
+	"github.com/DoomLordor/go-apiserver/faults"
+	"github.com/DoomLordor/go-apiserver/features"
 	"github.com/DoomLordor/go-apiserver/grpc"
+	"github.com/DoomLordor/go-apiserver/inflight"
+	"github.com/DoomLordor/go-apiserver/killswitch"
+	"github.com/DoomLordor/go-apiserver/maintenance"
+	"github.com/DoomLordor/go-apiserver/memguard"
+	"github.com/DoomLordor/go-apiserver/metrics"
+	"github.com/DoomLordor/go-apiserver/ratelimit"
 	"github.com/DoomLordor/go-apiserver/rest"
+	"github.com/DoomLordor/go-apiserver/systemd"
+	"github.com/DoomLordor/go-apiserver/tuning"
+	"github.com/DoomLordor/go-apiserver/watchdog"
 )
 
-var ConfiguratorNotSetup = errors.New("configurator not setup")
+var (
+	ConfiguratorNotSetup = errors.New("configurator not setup")
+	ServerExited         = errors.New("server exited")
+)
 
 type APIServer struct {
+	config      Config
 	logger      *logger.Logger
 	httpServer  *rest.Server
 	debugServer *debug.Server
 	grpcServer  *grpc.Server
+	switches    *killswitch.Registry
+	maintenance *maintenance.Mode
+	inflight    *inflight.Tracker
+	watchdog    *watchdog.Watchdog
+	tuner       *tuning.Tuner
+	metrics     *metrics.Registry
+	features    *features.Store
+	memory      *memguard.Guard
+	capture     *capture.Recorder
+	faults      *faults.Injector
+	limiter     *ratelimit.Limiter
+	compressor  *compression.Compressor
+	systemd     *systemd.Notifier
+	logs        *debug.LogBuffer
+	varsKey     string
+	phase       *atomic.Value
+	exited      *atomic.Value
 }
 
 func NewServer(config Config) *APIServer {
-	return &APIServer{
+	s := &APIServer{
+		config:      config,
 		logger:      logger.NewLogger("server"),
 		httpServer:  rest.NewServer(config.Rest),
 		debugServer: debug.NewServer(config.Debug),
 		grpcServer:  grpc.NewServer(config.Grpc),
+		inflight:    inflight.NewTracker(),
+		metrics:     metrics.NewRegistry(config.Metrics),
+		capture:     capture.NewRecorder(config.Capture),
+		systemd:     systemd.NewNotifier(config.Systemd),
+		phase:       &atomic.Value{},
+		exited:      &atomic.Value{},
 	}
+	s.phase.Store(PhaseNew)
+	return s
+}
+
+// Registerer registers business metrics served by the debug server next to the server ones
+func (s *APIServer) Registerer() prometheus.Registerer {
+	return s.metrics.Registerer()
+}
+
+// AddHealthCheck gates systemd watchdog pings, a failing check lets systemd restart the service
+func (s *APIServer) AddHealthCheck(name string, check systemd.HealthCheck) {
+	s.systemd.AddHealthCheck(name, check)
 }
 
 func (s *APIServer) Configuration(context context.Context, configurator Configurator) error {
 	s.logger.Info().Msg("Server configuration")
+	s.setPhase(PhaseConfiguring)
 
 	err := s.configuration(context, configurator)
 	if err != nil {
 		s.logger.Err(err).Send()
+		return err
 	}
-	return err
+	s.setPhase(PhaseConfigured)
+	return nil
 }
 
 func (s *APIServer) configuration(context context.Context, configurator Configurator) error {
@@ -47,36 +106,223 @@ func (s *APIServer) configuration(context context.Context, configurator Configur
 		return ConfiguratorNotSetup
 	}
 
+	err := s.registerVars()
+	if err != nil {
+		return err
+	}
+
+	tuner, err := tuning.NewTuner(s.config.Tuning, s.metrics.Registerer())
+	if err != nil {
+		return err
+	}
+	s.tuner = tuner
+	s.tuner.Apply()
+
 	adapter, err := configurator.Configure(context)
 	if err != nil {
 		return err
 	}
 
+	for _, collector := range adapter.Collectors {
+		err = s.metrics.Registerer().Register(collector)
+		if err != nil {
+			return err
+		}
+	}
+
+	s.switches, err = killswitch.NewRegistry(s.config.KillSwitch, s.metrics.Registerer())
+	if err != nil {
+		return err
+	}
+
+	s.features, err = features.NewStore(s.config.Features)
+	if err != nil {
+		return err
+	}
+
+	err = s.features.Register(adapter.Flags...)
+	if err != nil {
+		return err
+	}
+
+	s.maintenance, err = maintenance.NewMode(s.config.Maintenance, s.metrics.Registerer())
+	if err != nil {
+		return err
+	}
+
+	s.memory, err = memguard.NewGuard(s.config.MemoryGuard, s.metrics.Registerer())
+	if err != nil {
+		return err
+	}
+
+	s.watchdog, err = watchdog.NewWatchdog(s.config.Watchdog, s.inflight, s.metrics.Registerer())
+	if err != nil {
+		return err
+	}
+
+	s.faults, err = faults.NewInjector(s.config.Faults, s.metrics.Registerer())
+	if err != nil {
+		return err
+	}
+
+	s.limiter, err = ratelimit.NewLimiter(s.config.RateLimit, s.metrics.Registerer())
+	if err != nil {
+		return err
+	}
+	if adapter.RateLimitStore != nil {
+		s.limiter.SetStore(adapter.RateLimitStore)
+	}
+	if adapter.RateLimitKey != nil {
+		s.limiter.SetKeyFunc(adapter.RateLimitKey)
+	}
+
+	s.compressor, err = compression.NewCompressor(s.config.Compression, s.metrics.Registerer())
+	if err != nil {
+		return err
+	}
+
+	errorMapper := s.httpServer.Errors()
+	errorMapper.Add(adapter.Errors...)
+
 	if s.httpServer.Active() {
-		err = s.httpServer.Configuration(adapter.Api, adapter.Auth, adapter.Tracer)
+		if s.compressor.Active() {
+			// first so that other middlewares see plain request and response bodies
+			s.httpServer.Use(s.compressor.Middleware)
+		}
+		s.httpServer.Use(
+			s.memory.Middleware,
+			s.limiter.Middleware,
+			s.inflight.RestMiddleware,
+			s.maintenance.Middleware,
+			s.switches.RestMiddleware,
+			s.features.Middleware,
+			s.capture.Middleware,
+		)
+		s.httpServer.UseWs(
+			s.memory.Middleware,
+			s.limiter.Middleware,
+			s.inflight.WsMiddleware,
+			s.maintenance.Middleware,
+			s.switches.WsMiddleware,
+			s.features.Middleware,
+		)
+		s.httpServer.UseAfterAuth(s.inflight.UserMiddleware, s.limiter.UserMiddleware)
+		s.httpServer.OnSpan(s.inflight.Trace)
+		if s.faults.Active() {
+			s.httpServer.UseAfterAuth(s.faults.Middleware)
+		}
+		err = s.httpServer.Configuration(adapter.Api, adapter.Auth, adapter.Tracer, s.metrics.Registerer())
 		if err != nil {
 			return err
 		}
 	}
 
 	if s.grpcServer.Active() {
-		err = s.grpcServer.Configuration(adapter.Grps, adapter.Tracer)
+		s.grpcServer.UseUnary(
+			s.memory.UnaryInterceptor(),
+			s.inflight.UnaryInterceptor(),
+			s.maintenance.UnaryInterceptor(),
+			s.switches.UnaryInterceptor(),
+			s.features.UnaryInterceptor(),
+			s.capture.UnaryInterceptor(),
+		)
+		s.grpcServer.UseStream(
+			s.memory.StreamInterceptor(),
+			s.inflight.StreamInterceptor(),
+			s.maintenance.StreamInterceptor(),
+			s.switches.StreamInterceptor(),
+			s.features.StreamInterceptor(),
+		)
+		if s.faults.Active() {
+			s.grpcServer.UseUnary(s.faults.UnaryInterceptor())
+			s.grpcServer.UseStream(s.faults.StreamInterceptor())
+		}
+		s.grpcServer.UseUnary(errorMapper.UnaryInterceptor())
+		s.grpcServer.UseStream(errorMapper.StreamInterceptor())
+		err = s.grpcServer.Configuration(adapter.Grps, adapter.Tracer, s.metrics.Registerer())
 		if err != nil {
 			return err
 		}
 	}
 
 	if s.debugServer.Active() {
-		s.debugServer.Configuration()
+		modules := []debug.Module{
+			&introspection{httpServer: s.httpServer, grpcServer: s.grpcServer},
+			s.switches,
+			s.maintenance,
+			s.inflight,
+			s.watchdog,
+			s.features,
+			s.memory,
+			s.capture,
+			s.faults,
+			s.limiter,
+		}
+		if s.logs != nil {
+			modules = append(modules, s.logs)
+			s.debugServer.OnShutdown(s.logs.Shutdown)
+		}
+		s.debugServer.AddReadinessCheck("maintenance", s.maintenance.ReadinessCheck)
+		s.debugServer.AddReadinessCheck("memory", s.memory.ReadinessCheck)
+		s.debugServer.AddInfo("runtime_tuning", s.tuner.Decision)
+		s.debugServer.Configuration(s.metrics.Gatherer(), modules)
 	}
 
 	return nil
 }
 
 func (s *APIServer) Start() {
-	go s.httpServer.Start()
-	go s.grpcServer.Start()
-	go s.debugServer.Start()
+	s.setPhase(PhaseStarting)
+	if s.maintenance != nil {
+		s.maintenance.Start()
+	}
+	if s.watchdog != nil {
+		s.watchdog.Start()
+	}
+	if s.memory != nil {
+		s.memory.Start()
+	}
+
+	if err := s.httpServer.Listen(); err != nil {
+		s.logger.Fatal().Err(err).Send()
+	}
+	errDebug := s.debugServer.Listen()
+	if errDebug != nil {
+		s.logger.Err(errDebug).Send()
+	}
+
+	s.serve("rest", s.httpServer.Active(), s.httpServer.Start)
+	s.serve("grpc", s.grpcServer.Active(), s.grpcServer.Start)
+	s.serve("debug", s.debugServer.Active(), s.debugServer.Start)
+
+	s.systemd.AddHealthCheck("servers", s.serversCheck)
+	if errDebug != nil {
+		// without READY systemd fails the start instead of running a service it can not probe
+		s.phase.Store(PhaseServing)
+		s.systemd.Status("debug server failed: " + errDebug.Error())
+	} else {
+		s.setPhase(PhaseServing)
+	}
+	s.systemd.Start()
+}
+
+func (s *APIServer) serve(name string, active bool, start func()) {
+	if !active {
+		return
+	}
+	go func() {
+		start()
+		if phase := s.Phase(); phase != PhaseStopping && phase != PhaseStopped {
+			s.exited.Store(name)
+		}
+	}()
+}
+
+func (s *APIServer) serversCheck() error {
+	if name, ok := s.exited.Load().(string); ok {
+		return fmt.Errorf("%w: %s", ServerExited, name)
+	}
+	return nil
 }
 
 func (s *APIServer) stop(ctx context.Context) error {
@@ -102,6 +348,18 @@ func (s *APIServer) stop(ctx context.Context) error {
 func (s *APIServer) Stop(ctx context.Context, shutdown Shutdown) error {
 	errs := make([]error, 0, 10)
 
+	s.setPhase(PhaseStopping)
+	s.systemd.Stop()
+	if s.maintenance != nil {
+		s.maintenance.Stop()
+	}
+	if s.watchdog != nil {
+		s.watchdog.Stop()
+	}
+	if s.memory != nil {
+		s.memory.Stop()
+	}
+
 	errStop := s.stop(ctx)
 	if errStop != nil {
 		errs = append(errs, errStop)
@@ -118,6 +376,8 @@ func (s *APIServer) Stop(ctx context.Context, shutdown Shutdown) error {
 		}
 	}
 
+	s.unpublishVars()
+	s.setPhase(PhaseStopped)
 	s.logger.Info().Msg("Server stop")
 	return errors.Join(errs...)
 }
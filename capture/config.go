@@ -0,0 +1,8 @@
+package capture
+
+type Config struct {
+	Dir           string   `env:"CAPTURE_DIR" envDefault:""`
+	MaxBodySize   int      `env:"CAPTURE_MAX_BODY_SIZE" envDefault:"65536"`
+	RedactHeaders []string `env:"CAPTURE_REDACT_HEADERS" envSeparator:"," envDefault:"Authorization,Cookie,Set-Cookie"`
+	RedactFields  []string `env:"CAPTURE_REDACT_FIELDS" envSeparator:"," envDefault:"password,token,secret"`
+}
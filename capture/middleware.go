@@ -0,0 +1,160 @@
+package capture
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/DoomLordor/go-apiserver/rest"
+)
+
+type responseWriter struct {
+	http.ResponseWriter
+	status    int
+	body      *bytes.Buffer
+	limit     int
+	truncated bool
+}
+
+func (w *responseWriter) WriteHeader(code int) {
+	w.status = code
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *responseWriter) Write(p []byte) (int, error) {
+	if room := w.limit - w.body.Len(); room > 0 {
+		if len(p) > room {
+			w.body.Write(p[:room])
+			w.truncated = true
+		} else {
+			w.body.Write(p)
+		}
+	} else if len(p) > 0 {
+		w.truncated = true
+	}
+	return w.ResponseWriter.Write(p)
+}
+
+func (w *responseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (w *responseWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (r *Recorder) Middleware(next http.Handler) http.Handler {
+	f := func(w http.ResponseWriter, req *http.Request) {
+		path := rest.PathTemplate(req)
+		if !r.match(TypeRest, req.Method, path) {
+			next.ServeHTTP(w, req)
+			return
+		}
+
+		body, truncated, err := r.readBody(req)
+		if err != nil {
+			next.ServeHTTP(w, req)
+			return
+		}
+
+		writer := &responseWriter{
+			ResponseWriter: w,
+			status:         http.StatusOK,
+			body:           &bytes.Buffer{},
+			limit:          r.config.MaxBodySize,
+		}
+		start := time.Now()
+		next.ServeHTTP(writer, req)
+
+		record := &Record{
+			Type:           TypeRest,
+			Time:           start,
+			Duration:       time.Since(start).Milliseconds(),
+			Method:         req.Method,
+			Path:           path,
+			URL:            req.RequestURI,
+			Header:         r.redactor.header(req.Header),
+			BodyTruncated:  truncated || writer.truncated,
+			Status:         writer.status,
+			ResponseHeader: r.redactor.header(writer.Header()),
+		}
+		record.Body, record.RawBody = r.redactor.body(body)
+		record.ResponseBody, record.RawResponse = r.redactor.body(writer.body.Bytes())
+		r.write(record)
+	}
+	return http.HandlerFunc(f)
+}
+
+// readBody reads up to the size limit and puts the consumed part back in front of the body
+func (r *Recorder) readBody(req *http.Request) ([]byte, bool, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, false, nil
+	}
+
+	body, err := io.ReadAll(io.LimitReader(req.Body, int64(r.config.MaxBodySize)+1))
+	if err != nil {
+		return nil, false, err
+	}
+
+	req.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(body), req.Body), req.Body}
+
+	if len(body) > r.config.MaxBodySize {
+		return body[:r.config.MaxBodySize], true, nil
+	}
+	return body, false, nil
+}
+
+func (r *Recorder) UnaryInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if !r.match(TypeGrpc, "", info.FullMethod) {
+			return handler(ctx, req)
+		}
+
+		start := time.Now()
+		resp, err := handler(ctx, req)
+
+		record := &Record{
+			Type:       TypeGrpc,
+			Time:       start,
+			Duration:   time.Since(start).Milliseconds(),
+			FullMethod: info.FullMethod,
+			Code:       status.Code(err).String(),
+		}
+		if md, ok := metadata.FromIncomingContext(ctx); ok {
+			record.Metadata = r.redactor.metadata(md)
+		}
+		record.RequestType, record.Request = r.message(req)
+		if err == nil {
+			record.ResponseType, record.Response = r.message(resp)
+		}
+		r.write(record)
+
+		return resp, err
+	}
+}
+
+func (r *Recorder) message(value any) (string, []byte) {
+	message, ok := value.(proto.Message)
+	if !ok {
+		return "", nil
+	}
+	data, err := protojson.Marshal(message)
+	if err != nil {
+		return "", nil
+	}
+	body, _ := r.redactor.body(data)
+	return string(proto.MessageName(message)), body
+}
@@ -0,0 +1,135 @@
+package capture
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	TypeRest = "rest"
+	TypeGrpc = "grpc"
+
+	redacted = "[REDACTED]"
+)
+
+type Record struct {
+	Type     string    `json:"type"`
+	Time     time.Time `json:"time"`
+	Duration int64     `json:"duration_ms"`
+
+	// REST
+	Method         string          `json:"method,omitempty"`
+	Path           string          `json:"path,omitempty"`
+	URL            string          `json:"url,omitempty"`
+	Header         http.Header     `json:"header,omitempty"`
+	Body           json.RawMessage `json:"body,omitempty"`
+	RawBody        []byte          `json:"raw_body,omitempty"`
+	BodyTruncated  bool            `json:"body_truncated,omitempty"`
+	Status         int             `json:"status,omitempty"`
+	ResponseHeader http.Header     `json:"response_header,omitempty"`
+	ResponseBody   json.RawMessage `json:"response_body,omitempty"`
+	RawResponse    []byte          `json:"raw_response,omitempty"`
+
+	// gRPC
+	FullMethod   string              `json:"full_method,omitempty"`
+	Metadata     map[string][]string `json:"metadata,omitempty"`
+	RequestType  string              `json:"request_type,omitempty"`
+	Request      json.RawMessage     `json:"request,omitempty"`
+	ResponseType string              `json:"response_type,omitempty"`
+	Response     json.RawMessage     `json:"response,omitempty"`
+	Code         string              `json:"code,omitempty"`
+}
+
+type redactor struct {
+	headers map[string]struct{}
+	fields  map[string]struct{}
+	// raw matches "field": "value" pairs in bodies which are not valid JSON, e.g. truncated ones
+	raw *regexp.Regexp
+}
+
+func newRedactor(headers, fields []string) *redactor {
+	r := &redactor{
+		headers: make(map[string]struct{}, len(headers)),
+		fields:  make(map[string]struct{}, len(fields)),
+	}
+	for _, header := range headers {
+		r.headers[http.CanonicalHeaderKey(strings.TrimSpace(header))] = struct{}{}
+	}
+
+	quoted := make([]string, 0, len(fields))
+	for _, field := range fields {
+		field = strings.ToLower(strings.TrimSpace(field))
+		if field == "" {
+			continue
+		}
+		r.fields[field] = struct{}{}
+		quoted = append(quoted, regexp.QuoteMeta(field))
+	}
+	if len(quoted) > 0 {
+		r.raw = regexp.MustCompile(`(?i)("(?:` + strings.Join(quoted, "|") + `)"\s*:\s*)("(?:[^"\\]|\\.)*"?|[^,}\]\s]+)`)
+	}
+	return r
+}
+
+func (r *redactor) header(header http.Header) http.Header {
+	res := header.Clone()
+	for name := range res {
+		if _, ok := r.headers[http.CanonicalHeaderKey(name)]; ok {
+			res[name] = []string{redacted}
+		}
+	}
+	return res
+}
+
+func (r *redactor) metadata(md map[string][]string) map[string][]string {
+	res := make(map[string][]string, len(md))
+	for name, values := range md {
+		if _, ok := r.headers[http.CanonicalHeaderKey(name)]; ok {
+			values = []string{redacted}
+		}
+		res[name] = values
+	}
+	return res
+}
+
+// body returns the redacted body as JSON when possible, as raw bytes otherwise
+func (r *redactor) body(data []byte) (json.RawMessage, []byte) {
+	if len(bytes.TrimSpace(data)) == 0 {
+		return nil, nil
+	}
+
+	var value any
+	if err := json.Unmarshal(data, &value); err == nil {
+		res, err := json.Marshal(r.value(value))
+		if err == nil {
+			return res, nil
+		}
+	}
+
+	if r.raw == nil {
+		return nil, data
+	}
+	return nil, r.raw.ReplaceAll(data, []byte(`${1}"`+redacted+`"`))
+}
+
+func (r *redactor) value(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		for key, item := range v {
+			if _, ok := r.fields[strings.ToLower(key)]; ok {
+				v[key] = redacted
+				continue
+			}
+			v[key] = r.value(item)
+		}
+	case []any:
+		for i, item := range v {
+			v[i] = r.value(item)
+		}
+	}
+	return value
+}
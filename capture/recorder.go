@@ -0,0 +1,228 @@
+package capture
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/DoomLordor/logger"
+)
+
+const (
+	defaultDuration    = time.Minute
+	defaultMaxBodySize = 64 * 1024
+)
+
+var (
+	AlreadyActive = errors.New("capture already active")
+	NotActive     = errors.New("capture not active")
+	InvalidType   = errors.New("invalid capture type")
+)
+
+type Filter struct {
+	// Type is rest, grpc or empty for both
+	Type string `json:"type,omitempty"`
+	// Method is the HTTP method of REST requests
+	Method string `json:"method,omitempty"`
+	// Path is a REST path template or a gRPC full method, a trailing * matches a prefix
+	Path string `json:"path,omitempty"`
+}
+
+func (f Filter) match(recordType, method, path string) bool {
+	if f.Type != "" && f.Type != recordType {
+		return false
+	}
+	if f.Method != "" && recordType == TypeRest && !strings.EqualFold(f.Method, method) {
+		return false
+	}
+	if f.Path == "" {
+		return true
+	}
+	if prefix, ok := strings.CutSuffix(f.Path, "*"); ok {
+		return strings.HasPrefix(path, prefix)
+	}
+	return f.Path == path
+}
+
+type StartRequest struct {
+	Duration    string `json:"duration"`
+	MaxRequests int    `json:"max_requests"`
+	Filter      Filter `json:"filter"`
+}
+
+type Session struct {
+	File        string    `json:"file"`
+	Filter      Filter    `json:"filter"`
+	StartedAt   time.Time `json:"started_at"`
+	Until       time.Time `json:"until"`
+	MaxRequests int       `json:"max_requests"`
+	Count       int       `json:"count"`
+	Active      bool      `json:"active"`
+}
+
+type Recorder struct {
+	config   Config
+	logger   *logger.Logger
+	redactor *redactor
+	active   *atomic.Bool
+	mu       *sync.Mutex
+	session  Session
+	file     *os.File
+	encoder  *json.Encoder
+	timer    *time.Timer
+}
+
+func NewRecorder(config Config) *Recorder {
+	if config.Dir == "" {
+		config.Dir = os.TempDir()
+	}
+	if config.MaxBodySize <= 0 {
+		config.MaxBodySize = defaultMaxBodySize
+	}
+	return &Recorder{
+		config:   config,
+		logger:   logger.NewLogger("capture"),
+		redactor: newRedactor(config.RedactHeaders, config.RedactFields),
+		active:   &atomic.Bool{},
+		mu:       &sync.Mutex{},
+	}
+}
+
+func (r *Recorder) Start(req StartRequest) (Session, error) {
+	switch req.Filter.Type {
+	case "", TypeRest, TypeGrpc:
+	default:
+		return Session{}, InvalidType
+	}
+
+	duration := defaultDuration
+	if req.Duration != "" {
+		var err error
+		duration, err = time.ParseDuration(req.Duration)
+		if err != nil {
+			return Session{}, err
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.session.Active {
+		return r.session, AlreadyActive
+	}
+
+	err := os.MkdirAll(r.config.Dir, 0o755)
+	if err != nil {
+		return Session{}, err
+	}
+
+	now := time.Now()
+	path, file, err := createFile(r.config.Dir, now)
+	if err != nil {
+		return Session{}, err
+	}
+
+	r.file = file
+	r.encoder = json.NewEncoder(file)
+	r.session = Session{
+		File:        path,
+		Filter:      req.Filter,
+		StartedAt:   now,
+		Until:       now.Add(duration),
+		MaxRequests: req.MaxRequests,
+		Active:      true,
+	}
+	r.timer = time.AfterFunc(duration, func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		// the timer may fire late, after this session was stopped and a new one started
+		if r.session.Active && r.session.StartedAt.Equal(now) {
+			_ = r.stop()
+		}
+	})
+	r.active.Store(true)
+
+	r.logger.Info().
+		Str("file", path).
+		Str("duration", duration.String()).
+		Int("max_requests", req.MaxRequests).
+		Str("type", req.Filter.Type).
+		Str("method", req.Filter.Method).
+		Str("path", req.Filter.Path).
+		Msg("Capture start")
+	return r.session, nil
+}
+
+func (r *Recorder) Stop() (Session, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.session.Active {
+		return r.session, NotActive
+	}
+	return r.session, r.stop()
+}
+
+func (r *Recorder) Status() Session {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.session
+}
+
+// stop must be called with the lock held
+func (r *Recorder) stop() error {
+	r.active.Store(false)
+	r.session.Active = false
+	r.timer.Stop()
+	err := r.file.Close()
+	r.logger.Info().Str("file", r.session.File).Int("count", r.session.Count).Msg("Capture stop")
+	return err
+}
+
+func (r *Recorder) match(recordType, method, path string) bool {
+	if !r.active.Load() {
+		return false
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.session.Active && r.session.Filter.match(recordType, method, path)
+}
+
+func (r *Recorder) write(record *Record) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.session.Active {
+		return
+	}
+
+	err := r.encoder.Encode(record)
+	if err != nil {
+		r.logger.Err(err).Str("file", r.session.File).Msg("Capture write")
+		return
+	}
+
+	r.session.Count++
+	if r.session.MaxRequests > 0 && r.session.Count >= r.session.MaxRequests {
+		err = r.stop()
+		if err != nil {
+			r.logger.Err(err).Str("file", r.session.File).Msg("Capture stop")
+		}
+	}
+}
+
+// createFile creates a new capture file, a counter is added when a file for the same instant exists
+func createFile(dir string, now time.Time) (string, *os.File, error) {
+	base := filepath.Join(dir, "capture-"+now.Format("20060102-150405.000000"))
+	path := base + ".jsonl"
+	for i := 2; ; i++ {
+		file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+		if !errors.Is(err, os.ErrExist) {
+			return path, file, err
+		}
+		path = fmt.Sprintf("%s-%d.jsonl", base, i)
+	}
+}
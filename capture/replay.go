@@ -0,0 +1,214 @@
+package capture
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+)
+
+var (
+	RestTargetMissing = errors.New("rest target url not set")
+	GrpcTargetMissing = errors.New("grpc target connection not set")
+)
+
+type ReplayOptions struct {
+	// RestURL is the base URL of the REST server, e.g. http://localhost:8000
+	RestURL string
+	Client  *http.Client
+	// GrpcConn is the connection to the gRPC server, message types must be linked into the binary
+	GrpcConn *grpc.ClientConn
+	// Header overrides recorded headers and metadata, e.g. the redacted Authorization
+	Header http.Header
+}
+
+type ReplayResult struct {
+	Type     string `json:"type"`
+	Target   string `json:"target"`
+	Expected string `json:"expected"`
+	Actual   string `json:"actual"`
+	Match    bool   `json:"match"`
+	Error    string `json:"error,omitempty"`
+}
+
+func ReadRecords(path string) ([]Record, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	records := make([]Record, 0, 100)
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		record := Record{}
+		err = json.Unmarshal(line, &record)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+	return records, scanner.Err()
+}
+
+// Replay sends the captured requests of the file in order and compares the status codes
+func Replay(ctx context.Context, path string, options ReplayOptions) ([]ReplayResult, error) {
+	records, err := ReadRecords(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if options.Client == nil {
+		options.Client = http.DefaultClient
+	}
+
+	results := make([]ReplayResult, 0, len(records))
+	for i := range records {
+		var result ReplayResult
+		switch records[i].Type {
+		case TypeRest:
+			result = replayRest(ctx, &records[i], options)
+		case TypeGrpc:
+			result = replayGrpc(ctx, &records[i], options)
+		default:
+			continue
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+func replayRest(ctx context.Context, record *Record, options ReplayOptions) ReplayResult {
+	result := ReplayResult{
+		Type:     TypeRest,
+		Target:   record.Method + " " + record.URL,
+		Expected: strconv.Itoa(record.Status),
+	}
+	if options.RestURL == "" {
+		result.Error = RestTargetMissing.Error()
+		return result
+	}
+
+	body := []byte(record.Body)
+	if body == nil {
+		body = record.RawBody
+	}
+	req, err := http.NewRequestWithContext(ctx, record.Method, strings.TrimSuffix(options.RestURL, "/")+record.URL, bytes.NewReader(body))
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	req.Header = record.Header.Clone()
+	if req.Header == nil {
+		req.Header = http.Header{}
+	}
+	req.Header.Del("Content-Length")
+	for name, values := range options.Header {
+		req.Header[name] = values
+	}
+
+	resp, err := options.Client.Do(req)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	_ = resp.Body.Close()
+
+	result.Actual = strconv.Itoa(resp.StatusCode)
+	result.Match = resp.StatusCode == record.Status
+	return result
+}
+
+func replayGrpc(ctx context.Context, record *Record, options ReplayOptions) ReplayResult {
+	result := ReplayResult{
+		Type:     TypeGrpc,
+		Target:   record.FullMethod,
+		Expected: record.Code,
+	}
+	if options.GrpcConn == nil {
+		result.Error = GrpcTargetMissing.Error()
+		return result
+	}
+
+	req, err := newMessage(record.RequestType, record.Request)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	resp, err := responseMessage(record.FullMethod, record.ResponseType)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	md := metadata.MD{}
+	for name, values := range record.Metadata {
+		// pseudo headers are set by the transport
+		if strings.HasPrefix(name, ":") || name == "content-type" || name == "user-agent" {
+			continue
+		}
+		md[name] = values
+	}
+	for name, values := range options.Header {
+		md[strings.ToLower(name)] = values
+	}
+
+	err = options.GrpcConn.Invoke(metadata.NewOutgoingContext(ctx, md), record.FullMethod, req, resp)
+	result.Actual = status.Code(err).String()
+	result.Match = result.Actual == record.Code
+	return result
+}
+
+func newMessage(name string, data []byte) (proto.Message, error) {
+	messageType, err := protoregistry.GlobalTypes.FindMessageByName(protoreflect.FullName(name))
+	if err != nil {
+		return nil, err
+	}
+	message := messageType.New().Interface()
+	if len(data) == 0 {
+		return message, nil
+	}
+	return message, protojson.Unmarshal(data, message)
+}
+
+// responseMessage finds the response type in the service descriptor when the call failed during capture
+func responseMessage(fullMethod, name string) (proto.Message, error) {
+	if name != "" {
+		return newMessage(name, nil)
+	}
+
+	serviceName, methodName, _ := strings.Cut(strings.TrimPrefix(fullMethod, "/"), "/")
+	descriptor, err := protoregistry.GlobalFiles.FindDescriptorByName(protoreflect.FullName(serviceName))
+	if err != nil {
+		return nil, err
+	}
+	service, ok := descriptor.(protoreflect.ServiceDescriptor)
+	if !ok {
+		return nil, protoregistry.NotFound
+	}
+	method := service.Methods().ByName(protoreflect.Name(methodName))
+	if method == nil {
+		return nil, protoregistry.NotFound
+	}
+	return newMessage(string(method.Output().FullName()), nil)
+}
@@ -0,0 +1,63 @@
+package capture
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/DoomLordor/go-apiserver/debug"
+)
+
+func (r *Recorder) RegistrationDebug() debug.RouteMap {
+	return debug.RouteMap{
+		"/capture": {
+			{
+				Methods:     []string{http.MethodGet},
+				Pattern:     "",
+				HandlerFunc: r.status,
+			},
+			{
+				Methods:     []string{http.MethodPost},
+				Pattern:     "/start",
+				HandlerFunc: r.start,
+			},
+			{
+				Methods:     []string{http.MethodPost},
+				Pattern:     "/stop",
+				HandlerFunc: r.stopCapture,
+			},
+		},
+	}
+}
+
+func (r *Recorder) status(_ *http.Request) (any, int, error) {
+	return r.Status(), http.StatusOK, nil
+}
+
+func (r *Recorder) start(req *http.Request) (any, int, error) {
+	startRequest := StartRequest{}
+	err := json.NewDecoder(req.Body).Decode(&startRequest)
+	if err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+
+	session, err := r.Start(startRequest)
+	switch {
+	case errors.Is(err, AlreadyActive):
+		return nil, http.StatusConflict, err
+	case err != nil:
+		return nil, http.StatusBadRequest, err
+	}
+	return session, http.StatusOK, nil
+}
+
+func (r *Recorder) stopCapture(_ *http.Request) (any, int, error) {
+	session, err := r.Stop()
+	switch {
+	case errors.Is(err, NotActive):
+		return nil, http.StatusConflict, err
+	case err != nil:
+		return nil, http.StatusInternalServerError, err
+	}
+	return session, http.StatusOK, nil
+}
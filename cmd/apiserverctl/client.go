@@ -0,0 +1,109 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+type client struct {
+	base string
+	http *http.Client
+}
+
+func newClient(addr string, timeout time.Duration) *client {
+	if !strings.Contains(addr, "://") {
+		addr = "http://" + addr
+	}
+	return &client{
+		base: strings.TrimSuffix(addr, "/"),
+		http: &http.Client{Timeout: timeout},
+	}
+}
+
+// statusError is returned for responses outside 2xx, body holds the decoded JSON if any
+type statusError struct {
+	code int
+	msg  string
+	body any
+}
+
+func (e *statusError) Error() string {
+	if e.msg == "" {
+		return fmt.Sprintf("debug server returned %d", e.code)
+	}
+	return fmt.Sprintf("debug server returned %d: %s", e.code, e.msg)
+}
+
+func (c *client) get(path string, query url.Values) (any, error) {
+	return c.do(http.MethodGet, path, query, nil)
+}
+
+func (c *client) post(path string, body any) (any, error) {
+	return c.do(http.MethodPost, path, nil, body)
+}
+
+func (c *client) do(method, path string, query url.Values, body any) (any, error) {
+	raw, code, err := c.raw(method, path, query, body)
+	if err != nil {
+		return nil, err
+	}
+
+	var res any
+	if len(bytes.TrimSpace(raw)) != 0 {
+		err = json.Unmarshal(raw, &res)
+		if err != nil {
+			return nil, fmt.Errorf("decode response: %w", err)
+		}
+	}
+
+	if code < 200 || code > 299 {
+		statusErr := &statusError{code: code, body: res}
+		if obj, ok := res.(map[string]any); ok {
+			statusErr.msg, _ = obj["error"].(string)
+		}
+		return nil, statusErr
+	}
+	return res, nil
+}
+
+func (c *client) raw(method, path string, query url.Values, body any) ([]byte, int, error) {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, 0, err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	target := c.base + path
+	if len(query) != 0 {
+		target += "?" + query.Encode()
+	}
+
+	req, err := http.NewRequest(method, target, reader)
+	if err != nil {
+		return nil, 0, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, 0, err
+	}
+	return data, resp.StatusCode, nil
+}
@@ -0,0 +1,644 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	notReady       = errors.New("server is not ready")
+	unknownProfile = errors.New("unknown profile")
+)
+
+var profiles = map[string]string{
+	"cpu":          "/debug/pprof/profile",
+	"heap":         "/debug/pprof/heap",
+	"goroutine":    "/debug/pprof/goroutine",
+	"block":        "/debug/pprof/block",
+	"threadcreate": "/debug/pprof/threadcreate",
+}
+
+var commands = map[string]command{
+	"health": {
+		help: "liveness and readiness checks",
+		run:  health,
+	},
+	"info": {
+		help: "build and runtime information",
+		run:  info,
+	},
+	"routes": {
+		help: "registered REST, WS and gRPC routes",
+		run:  routes,
+	},
+	"log": {
+		args: "get [module] | set <level> [-module name] [-ttl 10m]",
+		help: "show or change module log levels, a TTL reverts the change",
+		run:  logLevel,
+	},
+	"logs": {
+		args: "[-module name] [-level warn] [-request-id id] [-limit n]",
+		help: "recent log records kept by the debug server",
+		run:  logs,
+	},
+	"profile": {
+		args: "cpu|heap|goroutine|block|threadcreate [-seconds 30] [-o file]",
+		help: "capture a pprof profile and save it to a file",
+		run:  profile,
+	},
+	"switches": {
+		args: "[list] | disable|enable -type rest|ws|grpc -target path [-method GET] [-reason text]",
+		help: "list or flip kill switches",
+		run:  switches,
+	},
+	"maintenance": {
+		args: "[status] | on [-message text] | off",
+		help: "show or toggle maintenance mode",
+		run:  maintenance,
+	},
+	"features": {
+		args: "[list] | set <name> [-enabled=true] [-percentage 10] [-users a,b]",
+		help: "list or update feature flags",
+		run:  features,
+	},
+	"faults": {
+		args: "[list] | add [-type] [-path] [-method] [-header name=value] [-user] [-percentage] [-latency] [-status] [-code] [-abort] [-ttl] | rm <id> | clear",
+		help: "manage fault injection rules",
+		run:  faults,
+	},
+	"capture": {
+		args: "[status] | start [-duration 1m] [-max n] [-type rest] [-method GET] [-path /api/*] | stop",
+		help: "control traffic capture",
+		run:  capture,
+	},
+	"inflight": {
+		args: "[list] | cancel <id>",
+		help: "list or cancel in-flight requests",
+		run:  inflight,
+	},
+	"get": {
+		args: "<path>",
+		help: "print any other debug endpoint, e.g. /watchdog",
+		run:  get,
+	},
+}
+
+func health(e *env, args []string) error {
+	if len(args) != 0 {
+		return usageError
+	}
+
+	alive, err := e.client.get("/healthy", nil)
+	if err != nil {
+		return err
+	}
+
+	ready, err := e.client.get("/ready", nil)
+	var statusErr *statusError
+	if errors.As(err, &statusErr) && statusErr.code == http.StatusServiceUnavailable {
+		ready, err = statusErr.body, notReady
+	}
+
+	res := map[string]any{"alive": alive, "readiness": ready}
+	if obj, ok := alive.(map[string]any); ok {
+		res["alive"] = obj["alive"]
+	}
+	e.out.print(res)
+	return err
+}
+
+func info(e *env, args []string) error {
+	if len(args) != 0 {
+		return usageError
+	}
+	res, err := e.client.get("/info", nil)
+	if err != nil {
+		return err
+	}
+	e.out.print(res)
+	return nil
+}
+
+func routes(e *env, args []string) error {
+	if len(args) != 0 {
+		return usageError
+	}
+	res, err := e.client.get("/routes", nil)
+	if err != nil {
+		return err
+	}
+
+	obj, ok := res.(map[string]any)
+	if !ok || e.out.json {
+		e.out.print(res)
+		return nil
+	}
+	e.out.message("REST")
+	rest, _ := obj["rest"].([]any)
+	e.out.print(rest, "type", "methods", "path", "secure", "metrics", "deprecated")
+	e.out.message("\nGRPC")
+	grpc, _ := obj["grpc"].([]any)
+	methods := make([]any, 0)
+	for _, service := range grpc {
+		service, _ := service.(map[string]any)
+		list, _ := service["methods"].([]any)
+		for _, method := range list {
+			method, _ := method.(map[string]any)
+			methods = append(methods, map[string]any{
+				"service": service["name"],
+				"method":  method["name"],
+				"kind":    method["stream_kind"],
+			})
+		}
+	}
+	e.out.print(methods, "service", "method", "kind")
+	return nil
+}
+
+func logLevel(e *env, args []string) error {
+	action, args := subcommand(args, "get")
+	switch action {
+	case "get":
+		if len(args) > 1 {
+			return usageError
+		}
+		query := url.Values{}
+		if len(args) == 1 {
+			query.Set("module", args[0])
+		}
+		res, err := e.client.get("/logger", query)
+		if err != nil {
+			return err
+		}
+		if obj, ok := res.(map[string]any); ok && !e.out.json {
+			if modules, ok := obj["modules"].([]any); ok {
+				e.out.message("default: %s", scalar(obj["default"]))
+				e.out.print(modules, "module_name", "log_level", "expires_at")
+				return nil
+			}
+		}
+		e.out.print(res)
+		return nil
+	case "set":
+		fs := flag.NewFlagSet("set", flag.ContinueOnError)
+		module := fs.String("module", "", "")
+		ttl := fs.Duration("ttl", 0, "")
+		positional, err := parse(fs, args)
+		if err != nil {
+			return err
+		}
+		if len(positional) != 1 {
+			return usageError
+		}
+		body := map[string]any{"module_name": *module, "log_level": positional[0]}
+		if *ttl > 0 {
+			body["ttl"] = ttl.String()
+		}
+		res, err := e.client.post("/logger", body)
+		if err != nil {
+			return err
+		}
+		e.out.print(res)
+		return nil
+	default:
+		return usageError
+	}
+}
+
+func logs(e *env, args []string) error {
+	fs := flag.NewFlagSet("logs", flag.ContinueOnError)
+	module := fs.String("module", "", "")
+	level := fs.String("level", "", "")
+	requestId := fs.String("request-id", "", "")
+	limit := fs.Int("limit", 0, "")
+	positional, err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 0 {
+		return usageError
+	}
+
+	query := url.Values{}
+	for key, value := range map[string]string{"module": *module, "level": *level, "request_id": *requestId} {
+		if value != "" {
+			query.Set(key, value)
+		}
+	}
+	if *limit > 0 {
+		query.Set("limit", strconv.Itoa(*limit))
+	}
+
+	res, err := e.client.get("/logs", query)
+	if err != nil {
+		return err
+	}
+	records, ok := res.([]any)
+	if !ok || e.out.json {
+		e.out.print(res)
+		return nil
+	}
+	for _, record := range records {
+		data, _ := json.Marshal(record)
+		e.out.message("%s", data)
+	}
+	return nil
+}
+
+func profile(e *env, args []string) error {
+	fs := flag.NewFlagSet("profile", flag.ContinueOnError)
+	seconds := fs.Int("seconds", 30, "")
+	output := fs.String("o", "", "")
+	positional, err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return usageError
+	}
+
+	name := positional[0]
+	path, ok := profiles[name]
+	if !ok {
+		return fmt.Errorf("%w %q", unknownProfile, name)
+	}
+
+	query := url.Values{}
+	if name == "cpu" {
+		query.Set("seconds", strconv.Itoa(*seconds))
+		e.client.http.Timeout += time.Duration(*seconds) * time.Second
+		e.out.message("capturing cpu profile for %ds", *seconds)
+	}
+
+	data, code, err := e.client.raw(http.MethodGet, path, query, nil)
+	if err != nil {
+		return err
+	}
+	if code != http.StatusOK {
+		return &statusError{code: code, msg: strings.TrimSpace(string(data))}
+	}
+
+	file := *output
+	if file == "" {
+		file = fmt.Sprintf("%s-%s.pprof", name, time.Now().Format("20060102-150405"))
+	}
+	err = os.WriteFile(file, data, 0o644)
+	if err != nil {
+		return err
+	}
+
+	if e.out.json {
+		e.out.print(map[string]any{"profile": name, "file": file, "bytes": len(data)})
+		return nil
+	}
+	e.out.message("saved %s profile to %s (%d bytes), open with: go tool pprof %s", name, file, len(data), file)
+	return nil
+}
+
+func switches(e *env, args []string) error {
+	action, args := subcommand(args, "list")
+	switch action {
+	case "list":
+		if len(args) != 0 {
+			return usageError
+		}
+		res, err := e.client.get("/switches", nil)
+		if err != nil {
+			return err
+		}
+		e.out.print(res, "type", "method", "target", "reason", "disabled_at")
+		return nil
+	case "disable", "enable":
+		fs := flag.NewFlagSet(action, flag.ContinueOnError)
+		switchType := fs.String("type", "rest", "")
+		target := fs.String("target", "", "")
+		method := fs.String("method", "", "")
+		reason := fs.String("reason", "", "")
+		positional, err := parse(fs, args)
+		if err != nil {
+			return err
+		}
+		if len(positional) != 0 || *target == "" {
+			return usageError
+		}
+		body := map[string]any{"type": *switchType, "target": *target, "method": *method, "reason": *reason}
+		res, err := e.client.post("/switches/"+action, body)
+		if err != nil {
+			return err
+		}
+		e.out.print(res, "type", "method", "target", "reason", "disabled_at")
+		return nil
+	default:
+		return usageError
+	}
+}
+
+func maintenance(e *env, args []string) error {
+	action, args := subcommand(args, "status")
+	switch action {
+	case "status":
+		if len(args) != 0 {
+			return usageError
+		}
+		res, err := e.client.get("/maintenance", nil)
+		if err != nil {
+			return err
+		}
+		e.out.print(res)
+		return nil
+	case "on", "off":
+		fs := flag.NewFlagSet(action, flag.ContinueOnError)
+		message := fs.String("message", "", "")
+		positional, err := parse(fs, args)
+		if err != nil {
+			return err
+		}
+		if len(positional) != 0 {
+			return usageError
+		}
+		res, err := e.client.post("/maintenance", map[string]any{"enabled": action == "on", "message": *message})
+		if err != nil {
+			return err
+		}
+		e.out.print(res)
+		return nil
+	default:
+		return usageError
+	}
+}
+
+func features(e *env, args []string) error {
+	action, args := subcommand(args, "list")
+	switch action {
+	case "list":
+		if len(args) != 0 {
+			return usageError
+		}
+		res, err := e.client.get("/features", nil)
+		if err != nil {
+			return err
+		}
+		e.out.print(res, "name", "type", "enabled", "percentage", "users", "updated_at")
+		return nil
+	case "set":
+		fs := flag.NewFlagSet("set", flag.ContinueOnError)
+		enabled := &optionalBool{}
+		percentage := &optionalFloat{}
+		users := fs.String("users", "", "")
+		fs.Var(enabled, "enabled", "")
+		fs.Var(percentage, "percentage", "")
+		positional, err := parse(fs, args)
+		if err != nil {
+			return err
+		}
+		if len(positional) != 1 {
+			return usageError
+		}
+
+		body := map[string]any{}
+		if enabled.set {
+			body["enabled"] = enabled.value
+		}
+		if percentage.set {
+			body["percentage"] = percentage.value
+		}
+		if *users != "" {
+			body["users"] = strings.Split(*users, ",")
+		}
+		res, err := e.do(http.MethodPatch, "/features/"+url.PathEscape(positional[0]), body)
+		if err != nil {
+			return err
+		}
+		e.out.print(res)
+		return nil
+	default:
+		return usageError
+	}
+}
+
+func faults(e *env, args []string) error {
+	columns := []string{"id", "type", "method", "path", "user", "percentage", "latency", "status", "code", "abort", "hits", "expires_at"}
+	action, args := subcommand(args, "list")
+	switch action {
+	case "list":
+		if len(args) != 0 {
+			return usageError
+		}
+		res, err := e.client.get("/faults", nil)
+		if err != nil {
+			return err
+		}
+		e.out.print(res, columns...)
+		return nil
+	case "add":
+		fs := flag.NewFlagSet("add", flag.ContinueOnError)
+		rule := struct {
+			Type       string  `json:"type,omitempty"`
+			Path       string  `json:"path,omitempty"`
+			Method     string  `json:"method,omitempty"`
+			User       string  `json:"user,omitempty"`
+			Percentage float64 `json:"percentage"`
+			Latency    string  `json:"latency,omitempty"`
+			Status     int     `json:"status,omitempty"`
+			Code       string  `json:"code,omitempty"`
+			Abort      bool    `json:"abort,omitempty"`
+			TTL        string  `json:"ttl,omitempty"`
+
+			HeaderName  string `json:"header_name,omitempty"`
+			HeaderValue string `json:"header_value,omitempty"`
+		}{}
+		header := fs.String("header", "", "")
+		fs.StringVar(&rule.Type, "type", "", "")
+		fs.StringVar(&rule.Path, "path", "", "")
+		fs.StringVar(&rule.Method, "method", "", "")
+		fs.StringVar(&rule.User, "user", "", "")
+		fs.Float64Var(&rule.Percentage, "percentage", 100, "")
+		fs.StringVar(&rule.Latency, "latency", "", "")
+		fs.IntVar(&rule.Status, "status", 0, "")
+		fs.StringVar(&rule.Code, "code", "", "")
+		fs.BoolVar(&rule.Abort, "abort", false, "")
+		fs.StringVar(&rule.TTL, "ttl", "", "")
+		positional, err := parse(fs, args)
+		if err != nil {
+			return err
+		}
+		if len(positional) != 0 {
+			return usageError
+		}
+		if *header != "" {
+			name, value, _ := strings.Cut(*header, "=")
+			rule.HeaderName, rule.HeaderValue = name, value
+		}
+		res, err := e.client.post("/faults", rule)
+		if err != nil {
+			return err
+		}
+		e.out.print(res)
+		return nil
+	case "rm", "clear":
+		path := "/faults"
+		if action == "rm" {
+			if len(args) != 1 {
+				return usageError
+			}
+			path += "/" + url.PathEscape(args[0])
+		} else if len(args) != 0 {
+			return usageError
+		}
+		res, err := e.do(http.MethodDelete, path, nil)
+		if err != nil {
+			return err
+		}
+		e.out.print(res, columns...)
+		return nil
+	default:
+		return usageError
+	}
+}
+
+func capture(e *env, args []string) error {
+	action, args := subcommand(args, "status")
+	switch action {
+	case "status":
+		if len(args) != 0 {
+			return usageError
+		}
+		res, err := e.client.get("/capture", nil)
+		if err != nil {
+			return err
+		}
+		e.out.print(res)
+		return nil
+	case "start":
+		fs := flag.NewFlagSet("start", flag.ContinueOnError)
+		duration := fs.Duration("duration", time.Minute, "")
+		maxRequests := fs.Int("max", 0, "")
+		filter := map[string]string{}
+		for _, name := range []string{"type", "method", "path"} {
+			name := name
+			fs.Func(name, "", func(value string) error {
+				filter[name] = value
+				return nil
+			})
+		}
+		positional, err := parse(fs, args)
+		if err != nil {
+			return err
+		}
+		if len(positional) != 0 {
+			return usageError
+		}
+		body := map[string]any{"duration": duration.String(), "max_requests": *maxRequests, "filter": filter}
+		res, err := e.client.post("/capture/start", body)
+		if err != nil {
+			return err
+		}
+		e.out.print(res)
+		return nil
+	case "stop":
+		if len(args) != 0 {
+			return usageError
+		}
+		res, err := e.client.post("/capture/stop", nil)
+		if err != nil {
+			return err
+		}
+		e.out.print(res)
+		return nil
+	default:
+		return usageError
+	}
+}
+
+func inflight(e *env, args []string) error {
+	columns := []string{"id", "type", "method", "path", "age", "user", "request_id", "cancelled"}
+	action, args := subcommand(args, "list")
+	switch action {
+	case "list":
+		if len(args) != 0 {
+			return usageError
+		}
+		res, err := e.client.get("/inflight", nil)
+		if err != nil {
+			return err
+		}
+		e.out.print(res, columns...)
+		return nil
+	case "cancel":
+		if len(args) != 1 {
+			return usageError
+		}
+		res, err := e.client.post("/inflight/"+url.PathEscape(args[0])+"/cancel", nil)
+		if err != nil {
+			return err
+		}
+		e.out.print(res)
+		return nil
+	default:
+		return usageError
+	}
+}
+
+func get(e *env, args []string) error {
+	if len(args) != 1 {
+		return usageError
+	}
+	target, err := url.Parse(args[0])
+	if err != nil {
+		return err
+	}
+	path := target.Path
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	res, err := e.client.get(path, target.Query())
+	if err != nil {
+		return err
+	}
+	e.out.print(res)
+	return nil
+}
+
+func (e *env) do(method, path string, body any) (any, error) {
+	return e.client.do(method, path, nil, body)
+}
+
+// optionalBool and optionalFloat tell an unset flag from a zero value
+type optionalBool struct {
+	set   bool
+	value bool
+}
+
+func (o *optionalBool) String() string   { return strconv.FormatBool(o.value) }
+func (o *optionalBool) IsBoolFlag() bool { return true }
+func (o *optionalBool) Set(value string) error {
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		return err
+	}
+	o.set, o.value = true, parsed
+	return nil
+}
+
+type optionalFloat struct {
+	set   bool
+	value float64
+}
+
+func (o *optionalFloat) String() string { return strconv.FormatFloat(o.value, 'f', -1, 64) }
+func (o *optionalFloat) Set(value string) error {
+	parsed, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return err
+	}
+	o.set, o.value = true, parsed
+	return nil
+}
@@ -0,0 +1,132 @@
+// Command apiserverctl operates a running go-apiserver through its debug server.
+//
+// Usage:
+//
+//	apiserverctl [-addr localhost:8080] [-json] [-timeout 30s] <command> [arguments]
+//
+// Run apiserverctl help to list commands.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+var usageError = errors.New("usage")
+
+type env struct {
+	client *client
+	out    *printer
+	stderr io.Writer
+}
+
+type command struct {
+	args string
+	help string
+	run  func(e *env, args []string) error
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdout, stderr io.Writer) int {
+	addr := os.Getenv("APISERVERCTL_ADDR")
+	if addr == "" {
+		addr = "localhost:8080"
+	}
+
+	fs := flag.NewFlagSet("apiserverctl", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.StringVar(&addr, "addr", addr, "debug server address, APISERVERCTL_ADDR by default")
+	jsonOutput := fs.Bool("json", false, "print raw JSON responses")
+	timeout := fs.Duration("timeout", 30*time.Second, "request timeout, profiles add their duration")
+	fs.Usage = func() { usage(fs, stderr) }
+
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() == 0 || fs.Arg(0) == "help" {
+		usage(fs, stderr)
+		return 2
+	}
+
+	cmd, ok := commands[fs.Arg(0)]
+	if !ok {
+		fmt.Fprintf(stderr, "unknown command %q\n", fs.Arg(0))
+		usage(fs, stderr)
+		return 2
+	}
+
+	e := &env{
+		client: newClient(addr, *timeout),
+		out:    &printer{w: stdout, json: *jsonOutput},
+		stderr: stderr,
+	}
+	err := cmd.run(e, fs.Args()[1:])
+	switch {
+	case err == nil:
+		return 0
+	case errors.Is(err, usageError):
+		if err != usageError {
+			fmt.Fprintln(stderr, err)
+		}
+		fmt.Fprintf(stderr, "usage: apiserverctl %s\n", strings.TrimSpace(fs.Arg(0)+" "+cmd.args))
+		return 2
+	default:
+		var statusErr *statusError
+		if errors.As(err, &statusErr) && statusErr.body != nil && *jsonOutput {
+			e.out.print(statusErr.body)
+		}
+		fmt.Fprintln(stderr, "error:", err)
+		return 1
+	}
+}
+
+func usage(fs *flag.FlagSet, w io.Writer) {
+	fmt.Fprintln(w, "usage: apiserverctl [flags] <command> [arguments]")
+	fmt.Fprintln(w, "\nflags:")
+	fs.SetOutput(w)
+	fs.PrintDefaults()
+	fmt.Fprintln(w, "\ncommands:")
+
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(w, "  %s\n    \t%s\n", strings.TrimSpace(name+" "+commands[name].args), commands[name].help)
+	}
+}
+
+// parse parses flags placed anywhere between positional arguments and returns the positional ones
+func parse(fs *flag.FlagSet, args []string) ([]string, error) {
+	fs.SetOutput(io.Discard)
+	positional := make([]string, 0, len(args))
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, fmt.Errorf("%w: %s", usageError, err)
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+// subcommand splits args into the action and its arguments, def is used when args is empty
+func subcommand(args []string, def string) (string, []string) {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		return def, args
+	}
+	return args[0], args[1:]
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+type printer struct {
+	w    io.Writer
+	json bool
+}
+
+// print writes v as indented JSON or as human readable text,
+// columns pick and order the table columns for lists of objects
+func (p *printer) print(v any, columns ...string) {
+	if p.json {
+		encoder := json.NewEncoder(p.w)
+		encoder.SetIndent("", "  ")
+		_ = encoder.Encode(v)
+		return
+	}
+
+	switch value := v.(type) {
+	case []any:
+		p.table(value, columns)
+	case map[string]any:
+		p.object(value, "")
+	case nil:
+	default:
+		fmt.Fprintln(p.w, scalar(value))
+	}
+}
+
+func (p *printer) message(format string, args ...any) {
+	if p.json {
+		return
+	}
+	fmt.Fprintf(p.w, format+"\n", args...)
+}
+
+func (p *printer) object(obj map[string]any, indent string) {
+	for _, key := range sortedKeys(obj) {
+		switch value := obj[key].(type) {
+		case map[string]any:
+			fmt.Fprintf(p.w, "%s%s:\n", indent, key)
+			p.object(value, indent+"  ")
+		case []any:
+			if len(value) != 0 && isObject(value[0]) {
+				fmt.Fprintf(p.w, "%s%s:\n", indent, key)
+				p.tableIndent(value, nil, indent+"  ")
+				continue
+			}
+			fmt.Fprintf(p.w, "%s%s: %s\n", indent, key, scalar(value))
+		default:
+			fmt.Fprintf(p.w, "%s%s: %s\n", indent, key, scalar(value))
+		}
+	}
+}
+
+func (p *printer) table(rows []any, columns []string) {
+	p.tableIndent(rows, columns, "")
+}
+
+func (p *printer) tableIndent(rows []any, columns []string, indent string) {
+	if len(rows) == 0 {
+		fmt.Fprintf(p.w, "%s(none)\n", indent)
+		return
+	}
+	if !isObject(rows[0]) {
+		for _, row := range rows {
+			fmt.Fprintf(p.w, "%s%s\n", indent, scalar(row))
+		}
+		return
+	}
+
+	if len(columns) == 0 {
+		seen := make(map[string]any)
+		for _, row := range rows {
+			for key := range row.(map[string]any) {
+				seen[key] = nil
+			}
+		}
+		columns = sortedKeys(seen)
+	}
+
+	tw := tabwriter.NewWriter(p.w, 0, 0, 2, ' ', 0)
+	header := make([]string, len(columns))
+	for i, column := range columns {
+		header[i] = strings.ToUpper(column)
+	}
+	fmt.Fprintf(tw, "%s%s\n", indent, strings.Join(header, "\t"))
+	for _, row := range rows {
+		obj, _ := row.(map[string]any)
+		cells := make([]string, len(columns))
+		for i, column := range columns {
+			cells[i] = scalar(obj[column])
+		}
+		fmt.Fprintf(tw, "%s%s\n", indent, strings.Join(cells, "\t"))
+	}
+	_ = tw.Flush()
+}
+
+func scalar(v any) string {
+	switch value := v.(type) {
+	case nil:
+		return "-"
+	case string:
+		if value == "" {
+			return "-"
+		}
+		return value
+	case float64:
+		return fmt.Sprint(value)
+	case []any:
+		parts := make([]string, len(value))
+		for i, item := range value {
+			parts[i] = scalar(item)
+		}
+		return strings.Join(parts, ",")
+	case map[string]any:
+		data, _ := json.Marshal(value)
+		return string(data)
+	default:
+		return fmt.Sprint(value)
+	}
+}
+
+func isObject(v any) bool {
+	_, ok := v.(map[string]any)
+	return ok
+}
+
+func sortedKeys(obj map[string]any) []string {
+	keys := make([]string, 0, len(obj))
+	for key := range obj {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
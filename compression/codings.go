@@ -0,0 +1,78 @@
+package compression
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+)
+
+const (
+	EncodingGzip    = "gzip"
+	EncodingDeflate = "deflate"
+	EncodingZstd    = "zstd"
+	EncodingBrotli  = "br"
+)
+
+var UnknownEncoding = errors.New("unknown content encoding")
+
+// encodings are decoded in requests regardless of the ones configured for responses
+var encodings = []string{EncodingZstd, EncodingBrotli, EncodingGzip, EncodingDeflate}
+
+type encoder interface {
+	io.WriteCloser
+	Reset(w io.Writer)
+}
+
+// coding pools encoders of one content coding, deflate is the zlib format required by HTTP
+type coding struct {
+	name    string
+	pool    *sync.Pool
+	decoder func(r io.Reader) (io.ReadCloser, error)
+}
+
+func newCoding(name string) (*coding, error) {
+	c := &coding{name: name, pool: &sync.Pool{}}
+	switch name {
+	case EncodingGzip:
+		c.pool.New = func() any { return gzip.NewWriter(io.Discard) }
+		c.decoder = func(r io.Reader) (io.ReadCloser, error) { return gzip.NewReader(r) }
+	case EncodingDeflate:
+		c.pool.New = func() any { return zlib.NewWriter(io.Discard) }
+		c.decoder = zlib.NewReader
+	case EncodingZstd:
+		c.pool.New = func() any {
+			w, _ := zstd.NewWriter(io.Discard, zstd.WithEncoderConcurrency(1))
+			return w
+		}
+		c.decoder = func(r io.Reader) (io.ReadCloser, error) {
+			d, err := zstd.NewReader(r, zstd.WithDecoderConcurrency(1))
+			if err != nil {
+				return nil, err
+			}
+			return d.IOReadCloser(), nil
+		}
+	case EncodingBrotli:
+		c.pool.New = func() any { return brotli.NewWriter(io.Discard) }
+		c.decoder = func(r io.Reader) (io.ReadCloser, error) { return io.NopCloser(brotli.NewReader(r)), nil }
+	default:
+		return nil, fmt.Errorf("%w: %q", UnknownEncoding, name)
+	}
+	return c, nil
+}
+
+func (c *coding) encoder(w io.Writer) encoder {
+	e := c.pool.Get().(encoder)
+	e.Reset(w)
+	return e
+}
+
+func (c *coding) release(e encoder) {
+	e.Reset(io.Discard)
+	c.pool.Put(e)
+}
@@ -0,0 +1,181 @@
+package compression
+
+import (
+	"fmt"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/DoomLordor/logger"
+
+	"github.com/DoomLordor/go-apiserver/metrics"
+	"github.com/DoomLordor/go-apiserver/rest"
+)
+
+const (
+	directionRequest  = "request"
+	directionResponse = "response"
+)
+
+var DecompressedTooLarge = rest.NewError(http.StatusRequestEntityTooLarge, "request_too_large", "decompressed request body is too large")
+
+// Compressor compresses REST responses negotiated with Accept-Encoding and decompresses request bodies
+type Compressor struct {
+	config       Config
+	logger       *logger.Logger
+	codings      map[string]*coding
+	preferred    []*coding
+	types        map[string]bool
+	typePrefixes []string
+	ratio        *prometheus.HistogramVec
+	raw          *prometheus.CounterVec
+	encoded      *prometheus.CounterVec
+}
+
+func NewCompressor(config Config, registerer prometheus.Registerer) (*Compressor, error) {
+	codings := make(map[string]*coding, len(encodings))
+	for _, name := range encodings {
+		codings[name], _ = newCoding(name)
+	}
+
+	preferred := make([]*coding, 0, len(config.Encodings))
+	for _, name := range config.Encodings {
+		c, ok := codings[strings.ToLower(strings.TrimSpace(name))]
+		if !ok {
+			return nil, fmt.Errorf("%w: %q", UnknownEncoding, name)
+		}
+		preferred = append(preferred, c)
+	}
+
+	types := make(map[string]bool, len(config.ContentTypes))
+	prefixes := make([]string, 0)
+	for _, contentType := range config.ContentTypes {
+		contentType = strings.ToLower(strings.TrimSpace(contentType))
+		if prefix, ok := strings.CutSuffix(contentType, "*"); ok {
+			prefixes = append(prefixes, prefix)
+			continue
+		}
+		types[contentType] = true
+	}
+
+	ratio := prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "compression_ratio",
+			Help:    "Compressed to uncompressed body size ratio",
+			Buckets: []float64{0.05, 0.1, 0.2, 0.3, 0.5, 0.7, 0.9, 1},
+		},
+		[]string{"encoding", "direction"},
+	)
+	raw := prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "compression_uncompressed_bytes_total",
+			Help: "Total number of body bytes before compression or after decompression",
+		},
+		[]string{"encoding", "direction"},
+	)
+	encoded := prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "compression_compressed_bytes_total",
+			Help: "Total number of compressed body bytes",
+		},
+		[]string{"encoding", "direction"},
+	)
+
+	ratio, err := metrics.Register(registerer, ratio)
+	if err != nil {
+		return nil, err
+	}
+	raw, err = metrics.Register(registerer, raw)
+	if err != nil {
+		return nil, err
+	}
+	encoded, err = metrics.Register(registerer, encoded)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Compressor{
+		config:       config,
+		logger:       logger.NewLogger("compression"),
+		codings:      codings,
+		preferred:    preferred,
+		types:        types,
+		typePrefixes: prefixes,
+		ratio:        ratio,
+		raw:          raw,
+		encoded:      encoded,
+	}, nil
+}
+
+func (c *Compressor) Active() bool {
+	return c.config.Active
+}
+
+// negotiate picks the coding with the highest quality in Accept-Encoding, ties go to the server preference
+func (c *Compressor) negotiate(acceptEncoding string) *coding {
+	if acceptEncoding == "" || len(c.preferred) == 0 {
+		return nil
+	}
+
+	qualities := make(map[string]float64)
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		q := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+				q = parsed
+			}
+		}
+		qualities[strings.ToLower(strings.TrimSpace(name))] = q
+	}
+
+	candidates := make([]*coding, 0, len(c.preferred))
+	for _, coding := range c.preferred {
+		if c.quality(qualities, coding.name) > 0 {
+			candidates = append(candidates, coding)
+		}
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return c.quality(qualities, candidates[i].name) > c.quality(qualities, candidates[j].name)
+	})
+	return candidates[0]
+}
+
+func (c *Compressor) quality(qualities map[string]float64, name string) float64 {
+	if q, ok := qualities[name]; ok {
+		return q
+	}
+	return qualities["*"]
+}
+
+func (c *Compressor) compressible(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	if c.types[mediaType] {
+		return true
+	}
+	for _, prefix := range c.typePrefixes {
+		if strings.HasPrefix(mediaType, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *Compressor) observe(encoding, direction string, raw, encoded int64) {
+	if raw == 0 {
+		return
+	}
+	c.ratio.WithLabelValues(encoding, direction).Observe(float64(encoded) / float64(raw))
+	c.raw.WithLabelValues(encoding, direction).Add(float64(raw))
+	c.encoded.WithLabelValues(encoding, direction).Add(float64(encoded))
+}
@@ -0,0 +1,15 @@
+package compression
+
+type Config struct {
+	Active bool `env:"COMPRESSION" envDefault:"true"`
+	// Encodings in server preference order, br enables brotli
+	Encodings []string `env:"COMPRESSION_ENCODINGS" envSeparator:"," envDefault:"zstd,gzip,deflate"`
+	// MinSize is the smallest response body compressed, in bytes
+	MinSize int `env:"COMPRESSION_MIN_SIZE" envDefault:"1024"`
+	// ContentTypes are compressed media types, a type/* entry matches any subtype
+	ContentTypes []string `env:"COMPRESSION_CONTENT_TYPES" envSeparator:"," envDefault:"application/json,application/problem+json,application/xml,text/*"`
+	// MaxDecompressedSize limits request bodies after decompression, in bytes
+	MaxDecompressedSize int64 `env:"COMPRESSION_MAX_DECOMPRESSED_SIZE" envDefault:"10485760"`
+	// MaxRatio limits how many times a request body may grow when decompressed
+	MaxRatio float64 `env:"COMPRESSION_MAX_RATIO" envDefault:"100"`
+}
@@ -0,0 +1,251 @@
+package compression
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/DoomLordor/go-apiserver/rest"
+)
+
+type stateKey struct{}
+
+type state struct {
+	disabled bool
+}
+
+// Middleware decompresses request bodies and compresses responses of REST routes
+func (c *Compressor) Middleware(next http.Handler) http.Handler {
+	f := func(w http.ResponseWriter, r *http.Request) {
+		if encoding := r.Header.Get("Content-Encoding"); encoding != "" && !strings.EqualFold(encoding, "identity") {
+			body, code, err := c.decompress(r, encoding)
+			if err != nil {
+				c.logger.Warn().Str("encoding", encoding).Str("url", r.RequestURI).Str("warning", err.Error()).Send()
+				if code == http.StatusUnsupportedMediaType {
+					w.Header().Set("Accept-Encoding", strings.Join(encodings, ", "))
+				}
+				rest.WriteError(w, r, code, err)
+				return
+			}
+			defer body.Close()
+			r.Body = body
+			r.Header.Del("Content-Encoding")
+			r.Header.Del("Content-Length")
+			r.ContentLength = -1
+		}
+
+		w.Header().Add("Vary", "Accept-Encoding")
+		coding := c.negotiate(r.Header.Get("Accept-Encoding"))
+		if coding == nil || r.Method == http.MethodHead {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		s := &state{}
+		writer := &responseWriter{ResponseWriter: w, compressor: c, coding: coding, state: s}
+		defer writer.close()
+		next.ServeHTTP(writer, r.WithContext(context.WithValue(r.Context(), stateKey{}, s)))
+	}
+	return http.HandlerFunc(f)
+}
+
+// Disable opts a route out of response compression, add it to the route middlewares
+func Disable(next http.Handler) http.Handler {
+	f := func(w http.ResponseWriter, r *http.Request) {
+		if s, ok := r.Context().Value(stateKey{}).(*state); ok {
+			s.disabled = true
+		}
+		next.ServeHTTP(w, r)
+	}
+	return http.HandlerFunc(f)
+}
+
+func (c *Compressor) decompress(r *http.Request, encoding string) (io.ReadCloser, int, error) {
+	coding, ok := c.codings[strings.ToLower(strings.TrimSpace(encoding))]
+	if !ok {
+		return nil, http.StatusUnsupportedMediaType, UnknownEncoding
+	}
+
+	counter := &countReader{r: r.Body}
+	decoder, err := coding.decoder(counter)
+	if err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+	return &limitedReader{
+		decoder:    decoder,
+		body:       r.Body,
+		compressed: counter,
+		compressor: c,
+		encoding:   coding.name,
+	}, 0, nil
+}
+
+type countReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// minRatioSize lets small bodies grow past MaxRatio, highly repetitive payloads are often legitimate
+const minRatioSize = 64 << 10
+
+// limitedReader fails reads once the decompressed body exceeds the size or ratio limits
+type limitedReader struct {
+	decoder    io.ReadCloser
+	body       io.Closer
+	compressed *countReader
+	compressor *Compressor
+	encoding   string
+	n          int64
+	err        error
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	if l.err != nil {
+		return 0, l.err
+	}
+	n, err := l.decoder.Read(p)
+	l.n += int64(n)
+
+	config := l.compressor.config
+	tooLarge := config.MaxDecompressedSize > 0 && l.n > config.MaxDecompressedSize
+	if config.MaxRatio > 0 && l.n > minRatioSize && float64(l.n) > config.MaxRatio*float64(l.compressed.n) {
+		tooLarge = true
+	}
+	if tooLarge {
+		l.err = DecompressedTooLarge
+		return 0, l.err
+	}
+	if errors.Is(err, io.EOF) {
+		l.compressor.observe(l.encoding, directionRequest, l.n, l.compressed.n)
+	}
+	return n, err
+}
+
+func (l *limitedReader) Close() error {
+	return errors.Join(l.decoder.Close(), l.body.Close())
+}
+
+type flusher interface {
+	Flush() error
+}
+
+// responseWriter buffers the body until MinSize bytes decide whether it is compressed
+type responseWriter struct {
+	http.ResponseWriter
+	compressor *Compressor
+	coding     *coding
+	state      *state
+	code       int
+	buf        []byte
+	decided    bool
+	encoder    encoder
+	counter    *countWriter
+	raw        int64
+}
+
+func (w *responseWriter) WriteHeader(code int) {
+	if w.decided || code < http.StatusOK {
+		w.ResponseWriter.WriteHeader(code)
+		return
+	}
+	if w.code == 0 {
+		w.code = code
+	}
+}
+
+func (w *responseWriter) Write(p []byte) (int, error) {
+	if !w.decided {
+		w.buf = append(w.buf, p...)
+		if len(w.buf) < w.compressor.config.MinSize {
+			return len(p), nil
+		}
+		return len(p), w.decide()
+	}
+	if w.encoder != nil {
+		w.raw += int64(len(p))
+		return w.encoder.Write(p)
+	}
+	return w.ResponseWriter.Write(p)
+}
+
+func (w *responseWriter) decide() error {
+	w.decided = true
+	header := w.Header()
+	code := w.code
+	if code == 0 {
+		code = http.StatusOK
+	}
+
+	compress := !w.state.disabled &&
+		len(w.buf) >= w.compressor.config.MinSize &&
+		header.Get("Content-Encoding") == "" &&
+		code != http.StatusNoContent && code != http.StatusNotModified && code != http.StatusPartialContent &&
+		w.compressor.compressible(header.Get("Content-Type"))
+	if compress {
+		header.Set("Content-Encoding", w.coding.name)
+		header.Del("Content-Length")
+		w.counter = &countWriter{w: w.ResponseWriter}
+		w.encoder = w.coding.encoder(w.counter)
+	}
+
+	w.ResponseWriter.WriteHeader(code)
+	buf := w.buf
+	w.buf = nil
+	if len(buf) == 0 {
+		return nil
+	}
+	_, err := w.Write(buf)
+	return err
+}
+
+func (w *responseWriter) Flush() {
+	if !w.decided {
+		_ = w.decide()
+	}
+	if f, ok := w.encoder.(flusher); ok {
+		_ = f.Flush()
+	}
+	_ = http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+func (w *responseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (w *responseWriter) close() {
+	if !w.decided {
+		if w.code == 0 && len(w.buf) == 0 {
+			return
+		}
+		_ = w.decide()
+	}
+	if w.encoder == nil {
+		return
+	}
+	err := w.encoder.Close()
+	if err != nil {
+		w.compressor.logger.Err(err).Str("encoding", w.coding.name).Msg("Compression failed")
+	}
+	w.coding.release(w.encoder)
+	w.encoder = nil
+	w.compressor.observe(w.coding.name, directionResponse, w.raw, w.counter.n)
+}
+
+type countWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
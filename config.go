@@ -1,15 +1,48 @@
 package apiserver
 
 import (
+	"github.com/DoomLordor/go-apiserver/capture"
+	"github.com/DoomLordor/go-apiserver/compression"
 	"github.com/DoomLordor/go-apiserver/debug"
+	"github.com/DoomLordor/go-apiserver/faults"
+	"github.com/DoomLordor/go-apiserver/features"
 	"github.com/DoomLordor/go-apiserver/grpc"
+	"github.com/DoomLordor/go-apiserver/killswitch"
+	"github.com/DoomLordor/go-apiserver/maintenance"
+	"github.com/DoomLordor/go-apiserver/memguard"
+	"github.com/DoomLordor/go-apiserver/metrics"
+	"github.com/DoomLordor/go-apiserver/ratelimit"
 	"github.com/DoomLordor/go-apiserver/rest"
+	"github.com/DoomLordor/go-apiserver/systemd"
+	"github.com/DoomLordor/go-apiserver/tuning"
+	"github.com/DoomLordor/go-apiserver/watchdog"
 )
 
 type Config struct {
-	Rest  rest.Config
-	Debug debug.Config
-	Grpc  grpc.Config
+	// Name keys the server values in the "apiserver" expvar map, servers running with the same name get a numeric suffix
+	Name        string `env:"SERVER_NAME" envDefault:"apiserver"`
+	Rest        rest.Config
+	Debug       debug.Config
+	Grpc        grpc.Config
+	KillSwitch  killswitch.Config
+	Maintenance maintenance.Config
+	Watchdog    watchdog.Config
+	Tuning      tuning.Config
+	Metrics     metrics.Config
+	Features    features.Config
+	MemoryGuard memguard.Config
+	Capture     capture.Config
+	Faults      faults.Config
+	Systemd     systemd.Config
+	RateLimit   ratelimit.Config
+	Compression compression.Config
+}
+
+func (c *Config) name() string {
+	if c.Name == "" {
+		return "apiserver"
+	}
+	return c.Name
 }
 
 type JaegerConfig struct {
@@ -3,9 +3,12 @@ package apiserver
 import (
 	"context"
 
+	"github.com/prometheus/client_golang/prometheus"
 	"go.opentelemetry.io/otel/trace"
 
+	"github.com/DoomLordor/go-apiserver/features"
 	"github.com/DoomLordor/go-apiserver/grpc"
+	"github.com/DoomLordor/go-apiserver/ratelimit"
 	"github.com/DoomLordor/go-apiserver/rest"
 )
 
@@ -14,6 +17,16 @@ type Adapter struct {
 	Api    []rest.Api
 	Grps   []grpc.Grps
 	Tracer trace.Tracer
+	// Collectors are business metrics registered into the server registry
+	Collectors []prometheus.Collector
+	// Flags are default feature flags, flags persisted in the features file keep their state
+	Flags []features.Flag
+	// Errors map domain errors to rest.Error for both REST handlers and gRPC methods
+	Errors []rest.ErrorMapFunc
+	// RateLimitStore shares rate limits between instances, limits are kept in memory when nil
+	RateLimitStore ratelimit.Store
+	// RateLimitKey counts the global rate limit by a custom key instead of the configured one
+	RateLimitKey ratelimit.KeyFunc
 }
 
 type Configurator interface {
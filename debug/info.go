@@ -0,0 +1,78 @@
+package debug
+
+import (
+	"net/http"
+	"runtime"
+	"runtime/debug"
+	"sync"
+)
+
+type InfoFunc func() any
+
+type BuildInfo struct {
+	Path      string            `json:"path"`
+	Version   string            `json:"version"`
+	GoVersion string            `json:"go_version"`
+	Settings  map[string]string `json:"settings,omitempty"`
+}
+
+type RuntimeInfo struct {
+	GoVersion  string `json:"go_version"`
+	GOOS       string `json:"goos"`
+	GOARCH     string `json:"goarch"`
+	NumCPU     int    `json:"num_cpu"`
+	GOMAXPROCS int    `json:"gomaxprocs"`
+	Goroutines int    `json:"goroutines"`
+}
+
+type info struct {
+	mu       *sync.RWMutex
+	sections map[string]InfoFunc
+}
+
+func newInfo() *info {
+	return &info{
+		mu:       &sync.RWMutex{},
+		sections: make(map[string]InfoFunc, 10),
+	}
+}
+
+func (i *info) add(name string, f InfoFunc) {
+	i.mu.Lock()
+	i.sections[name] = f
+	i.mu.Unlock()
+}
+
+func (i *info) handler(_ *http.Request) (any, int, error) {
+	res := map[string]any{
+		"runtime": RuntimeInfo{
+			GoVersion:  runtime.Version(),
+			GOOS:       runtime.GOOS,
+			GOARCH:     runtime.GOARCH,
+			NumCPU:     runtime.NumCPU(),
+			GOMAXPROCS: runtime.GOMAXPROCS(0),
+			Goroutines: runtime.NumGoroutine(),
+		},
+	}
+
+	if build, ok := debug.ReadBuildInfo(); ok {
+		settings := make(map[string]string, len(build.Settings))
+		for _, setting := range build.Settings {
+			settings[setting.Key] = setting.Value
+		}
+		res["build"] = BuildInfo{
+			Path:      build.Main.Path,
+			Version:   build.Main.Version,
+			GoVersion: build.GoVersion,
+			Settings:  settings,
+		}
+	}
+
+	i.mu.RLock()
+	for name, f := range i.sections {
+		res[name] = f()
+	}
+	i.mu.RUnlock()
+
+	return res, http.StatusOK, nil
+}
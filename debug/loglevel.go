@@ -0,0 +1,159 @@
+package debug
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/DoomLordor/logger"
+)
+
+var InvalidLogLevel = errors.New("invalid log level")
+
+var logLevelNames = map[string]struct{}{
+	"trace": {}, "debug": {}, "info": {}, "warn": {}, "error": {},
+	"fatal": {}, "panic": {}, "no": {}, "disable": {},
+}
+
+// levels is global as the loggers it changes are
+var levels = newLogLevels()
+
+type LogLevel struct {
+	ModuleName string `json:"module_name"`
+	LogLevel   string `json:"log_level"`
+	// TTL reverts the module to its previous level when it passes
+	TTL       string     `json:"ttl,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+type LogLevelsResponse struct {
+	Default string     `json:"default"`
+	Modules []LogLevel `json:"modules"`
+}
+
+type setLogLevelResponse struct {
+	Status string `json:"status"`
+	LogLevel
+}
+
+// SetDefaultLogLevel records the level loggers are created with, it is reported for modules never changed
+func SetDefaultLogLevel(level string) {
+	level = strings.ToLower(level)
+	if _, ok := logLevelNames[level]; !ok {
+		level = "info"
+	}
+	levels.mu.Lock()
+	levels.defaultLevel = level
+	levels.mu.Unlock()
+}
+
+type logLevels struct {
+	mu           *sync.Mutex
+	defaultLevel string
+	modules      map[string]LogLevel
+	timers       map[string]*time.Timer
+}
+
+func newLogLevels() *logLevels {
+	return &logLevels{
+		mu:           &sync.Mutex{},
+		defaultLevel: "info",
+		modules:      make(map[string]LogLevel),
+		timers:       make(map[string]*time.Timer),
+	}
+}
+
+func (l *logLevels) get(r *http.Request) (any, int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if module := r.URL.Query().Get("module"); module != "" {
+		level, ok := l.modules[module]
+		if !ok {
+			level = LogLevel{ModuleName: module, LogLevel: l.defaultLevel}
+		}
+		return level, http.StatusOK, nil
+	}
+
+	res := LogLevelsResponse{Default: l.defaultLevel, Modules: make([]LogLevel, 0, len(l.modules))}
+	for _, level := range l.modules {
+		res.Modules = append(res.Modules, level)
+	}
+	sort.Slice(res.Modules, func(i, j int) bool {
+		return res.Modules[i].ModuleName < res.Modules[j].ModuleName
+	})
+	return res, http.StatusOK, nil
+}
+
+func (l *logLevels) set(r *http.Request) (any, int, error) {
+	level := LogLevel{}
+	err := json.NewDecoder(r.Body).Decode(&level)
+	if err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+
+	if level.ModuleName == "" {
+		level.ModuleName = logger.BaseLoggerName
+	}
+	if level.LogLevel == "" {
+		level.LogLevel = "info"
+	}
+	level.LogLevel = strings.ToLower(level.LogLevel)
+	if _, ok := logLevelNames[level.LogLevel]; !ok {
+		return nil, http.StatusBadRequest, InvalidLogLevel
+	}
+
+	var ttl time.Duration
+	if level.TTL != "" {
+		ttl, err = time.ParseDuration(level.TTL)
+		if err != nil {
+			return nil, http.StatusBadRequest, err
+		}
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	previous, changed := l.modules[level.ModuleName]
+	if timer, ok := l.timers[level.ModuleName]; ok {
+		timer.Stop()
+		delete(l.timers, level.ModuleName)
+	}
+
+	level.ExpiresAt = nil
+	if ttl > 0 {
+		expiresAt := time.Now().Add(ttl)
+		level.ExpiresAt = &expiresAt
+		l.timers[level.ModuleName] = time.AfterFunc(ttl, func() {
+			l.revert(level, previous, changed)
+		})
+	}
+
+	logger.SetLevel(level.ModuleName, level.LogLevel)
+	l.modules[level.ModuleName] = level
+	return setLogLevelResponse{Status: "ok", LogLevel: level}, http.StatusOK, nil
+}
+
+// revert restores the level a TTL change replaced unless it was changed again since
+func (l *logLevels) revert(level, previous LogLevel, changed bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	current, ok := l.modules[level.ModuleName]
+	if !ok || current.ExpiresAt != level.ExpiresAt {
+		return
+	}
+	delete(l.timers, level.ModuleName)
+
+	if changed && previous.ExpiresAt == nil {
+		logger.SetLevel(level.ModuleName, previous.LogLevel)
+		l.modules[level.ModuleName] = previous
+		return
+	}
+	logger.SetLevel(level.ModuleName, l.defaultLevel)
+	delete(l.modules, level.ModuleName)
+}
@@ -0,0 +1,224 @@
+package debug
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/DoomLordor/logger"
+)
+
+type logRecord struct {
+	raw       json.RawMessage
+	level     zerolog.Level
+	module    string
+	requestId string
+}
+
+type logFilter struct {
+	module    string
+	level     zerolog.Level
+	requestId string
+}
+
+func (f logFilter) match(record *logRecord) bool {
+	if f.module != "" && f.module != record.module {
+		return false
+	}
+	if f.requestId != "" && f.requestId != record.requestId {
+		return false
+	}
+	return record.level >= f.level
+}
+
+// LogBuffer keeps the most recent log records in memory, it expects zerolog JSON lines
+type LogBuffer struct {
+	mu          *sync.RWMutex
+	records     []*logRecord
+	next        int
+	full        bool
+	subscribers map[chan *logRecord]struct{}
+	shutdown    chan struct{}
+	once        *sync.Once
+}
+
+func NewLogBuffer(size int) *LogBuffer {
+	if size <= 0 {
+		size = 1000
+	}
+	return &LogBuffer{
+		mu:          &sync.RWMutex{},
+		records:     make([]*logRecord, size),
+		subscribers: make(map[chan *logRecord]struct{}, 10),
+		shutdown:    make(chan struct{}),
+		once:        &sync.Once{},
+	}
+}
+
+func (b *LogBuffer) Write(p []byte) (int, error) {
+	fields := struct {
+		Level     string `json:"level"`
+		Module    string `json:"module"`
+		RequestId any    `json:"requestId"`
+	}{}
+	if err := json.Unmarshal(p, &fields); err != nil {
+		return len(p), nil
+	}
+
+	record := &logRecord{
+		raw:    append(json.RawMessage{}, p...),
+		level:  logger.ParseLogLevel(fields.Level),
+		module: fields.Module,
+	}
+	if fields.RequestId != nil {
+		record.requestId = fmt.Sprint(fields.RequestId)
+	}
+	if fields.Level == "" {
+		record.level = zerolog.NoLevel
+	}
+
+	b.mu.Lock()
+	b.records[b.next] = record
+	b.next = (b.next + 1) % len(b.records)
+	if b.next == 0 {
+		b.full = true
+	}
+	for ch := range b.subscribers {
+		select {
+		case ch <- record:
+		default:
+		}
+	}
+	b.mu.Unlock()
+
+	return len(p), nil
+}
+
+// Shutdown disconnects live tail subscribers
+func (b *LogBuffer) Shutdown() {
+	b.once.Do(func() {
+		close(b.shutdown)
+	})
+}
+
+func (b *LogBuffer) list(filter logFilter, limit int) []json.RawMessage {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	size := b.next
+	start := 0
+	if b.full {
+		size = len(b.records)
+		start = b.next
+	}
+
+	res := make([]json.RawMessage, 0, size)
+	for i := 0; i < size; i++ {
+		record := b.records[(start+i)%len(b.records)]
+		if filter.match(record) {
+			res = append(res, record.raw)
+		}
+	}
+	if limit > 0 && len(res) > limit {
+		res = res[len(res)-limit:]
+	}
+	return res
+}
+
+func (b *LogBuffer) subscribe() chan *logRecord {
+	ch := make(chan *logRecord, 100)
+	b.mu.Lock()
+	b.subscribers[ch] = struct{}{}
+	b.mu.Unlock()
+	return ch
+}
+
+func (b *LogBuffer) unsubscribe(ch chan *logRecord) {
+	b.mu.Lock()
+	delete(b.subscribers, ch)
+	b.mu.Unlock()
+}
+
+func (b *LogBuffer) RegistrationDebug() RouteMap {
+	return RouteMap{
+		"/logs": {
+			{
+				Methods:     []string{http.MethodGet},
+				Pattern:     "",
+				HandlerFunc: b.recent,
+			},
+			{
+				Methods: []string{http.MethodGet},
+				Pattern: "/tail",
+				Handler: http.HandlerFunc(b.tail),
+			},
+		},
+	}
+}
+
+func (b *LogBuffer) recent(r *http.Request) (any, int, error) {
+	filter := parseLogFilter(r)
+	limit := 0
+	if value := r.URL.Query().Get("limit"); value != "" {
+		var err error
+		limit, err = strconv.Atoi(value)
+		if err != nil {
+			return nil, http.StatusBadRequest, err
+		}
+	}
+	return b.list(filter, limit), http.StatusOK, nil
+}
+
+func (b *LogBuffer) tail(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	_ = http.NewResponseController(w).SetWriteDeadline(time.Time{})
+
+	filter := parseLogFilter(r)
+	ch := b.subscribe()
+	defer b.unsubscribe(ch)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-b.shutdown:
+			return
+		case record := <-ch:
+			if !filter.match(record) {
+				continue
+			}
+			_, err := fmt.Fprintf(w, "data: %s\n\n", record.raw)
+			if err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+func parseLogFilter(r *http.Request) logFilter {
+	query := r.URL.Query()
+	filter := logFilter{
+		module:    query.Get("module"),
+		level:     zerolog.TraceLevel,
+		requestId: query.Get("request_id"),
+	}
+	if level := query.Get("level"); level != "" {
+		filter.level = logger.ParseLogLevel(level)
+	}
+	return filter
+}
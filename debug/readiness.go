@@ -0,0 +1,68 @@
+package debug
+
+import (
+	"net/http"
+	"sort"
+	"sync"
+)
+
+type ReadinessCheck func() error
+
+type ReadinessResponse struct {
+	Ready  bool              `json:"ready"`
+	Checks map[string]string `json:"checks,omitempty"`
+}
+
+type readiness struct {
+	mu     *sync.RWMutex
+	checks map[string]ReadinessCheck
+}
+
+func newReadiness() *readiness {
+	return &readiness{
+		mu:     &sync.RWMutex{},
+		checks: make(map[string]ReadinessCheck, 10),
+	}
+}
+
+func (r *readiness) add(name string, check ReadinessCheck) {
+	r.mu.Lock()
+	r.checks[name] = check
+	r.mu.Unlock()
+}
+
+func (r *readiness) check() ReadinessResponse {
+	r.mu.RLock()
+	names := make([]string, 0, len(r.checks))
+	for name := range r.checks {
+		names = append(names, name)
+	}
+	checks := make(map[string]ReadinessCheck, len(r.checks))
+	for name, check := range r.checks {
+		checks[name] = check
+	}
+	r.mu.RUnlock()
+	sort.Strings(names)
+
+	res := ReadinessResponse{Ready: true}
+	for _, name := range names {
+		err := checks[name]()
+		if err == nil {
+			continue
+		}
+		if res.Checks == nil {
+			res.Checks = make(map[string]string, len(names))
+		}
+		res.Ready = false
+		res.Checks[name] = err.Error()
+	}
+	return res
+}
+
+func (r *readiness) handler(_ *http.Request) (any, int, error) {
+	res := r.check()
+	if !res.Ready {
+		return res, http.StatusServiceUnavailable, nil
+	}
+	return res, http.StatusOK, nil
+}
@@ -0,0 +1,22 @@
+package debug
+
+import (
+	"net/http"
+)
+
+type HandlerFunc func(r *http.Request) (any, int, error)
+
+type Route struct {
+	Methods     []string
+	Pattern     string
+	HandlerFunc HandlerFunc
+	// Handler is served as is instead of HandlerFunc, e.g. for streaming responses
+	Handler http.Handler
+}
+
+type Routes []*Route
+type RouteMap map[string]Routes
+
+type Module interface {
+	RegistrationDebug() RouteMap
+}
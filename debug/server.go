@@ -3,11 +3,14 @@ package debug
 import (
 	"context"
 	"errors"
+	"expvar"
+	"net"
 	"net/http"
 	"net/http/pprof"
 	"time"
 
 	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 
 	"github.com/DoomLordor/logger"
@@ -17,7 +20,10 @@ type Server struct {
 	config     Config
 	router     *mux.Router
 	httpServer *http.Server
+	listener   net.Listener
 	logger     *logger.Logger
+	readiness  *readiness
+	info       *info
 }
 
 func NewServer(config Config) *Server {
@@ -30,16 +36,39 @@ func NewServer(config Config) *Server {
 		Handler:      router,
 	}
 	return &Server{
+		config:     config,
 		router:     router,
 		httpServer: httpServer,
 		logger:     logger.NewLogger("debug-server"),
+		readiness:  newReadiness(),
+		info:       newInfo(),
 	}
 }
 
-func (s *Server) Configuration() {
-	s.router.Handle("/metrics", promhttp.Handler()).Methods(http.MethodGet)
+func (s *Server) Configuration(gatherer prometheus.Gatherer, modules []Module) {
+	if gatherer == nil {
+		gatherer = prometheus.DefaultGatherer
+	}
+	s.router.Handle("/metrics", promhttp.HandlerFor(gatherer, promhttp.HandlerOpts{})).Methods(http.MethodGet)
 	s.router.HandleFunc("/healthy", healthCheckHandler).Methods(http.MethodGet)
-	s.router.HandleFunc("/logger", setLogLevel).Methods(http.MethodPost)
+	s.router.Handle("/logger", handleWrapper(levels.get)).Methods(http.MethodGet)
+	s.router.Handle("/logger", handleWrapper(levels.set)).Methods(http.MethodPost)
+	s.router.Handle("/ready", handleWrapper(s.readiness.handler)).Methods(http.MethodGet)
+	s.router.Handle("/info", handleWrapper(s.info.handler)).Methods(http.MethodGet)
+	s.router.Handle("/debug/vars", expvar.Handler()).Methods(http.MethodGet)
+
+	for _, module := range modules {
+		for prefix, routes := range module.RegistrationDebug() {
+			sub := s.router.PathPrefix(prefix).Subrouter()
+			for _, route := range routes {
+				handler := route.Handler
+				if handler == nil {
+					handler = handleWrapper(route.HandlerFunc)
+				}
+				sub.Handle(route.Pattern, handler).Methods(route.Methods...)
+			}
+		}
+	}
 
 	router := s.router.PathPrefix("/debug/pprof").Subrouter()
 	router.HandleFunc("/", pprof.Index)
@@ -52,16 +81,49 @@ func (s *Server) Configuration() {
 	router.Handle("/block", pprof.Handler("block"))
 }
 
+func (s *Server) OnShutdown(f func()) {
+	s.httpServer.RegisterOnShutdown(f)
+}
+
+func (s *Server) AddInfo(name string, f InfoFunc) {
+	s.info.add(name, f)
+}
+
+func (s *Server) AddReadinessCheck(name string, check ReadinessCheck) {
+	s.readiness.add(name, check)
+}
+
+func (s *Server) Ready() bool {
+	return s.readiness.check().Ready
+}
+
 func (s *Server) Start() {
 	if !s.Active() {
 		return
 	}
+	if err := s.Listen(); err != nil {
+		s.logger.Err(err).Send()
+		return
+	}
 	s.logger.Info().Msg("Server debug start")
-	if err := s.httpServer.ListenAndServe(); err != nil {
+	if err := s.httpServer.Serve(s.listener); err != nil {
 		s.logger.Err(err).Send()
 	}
 }
 
+// Listen binds the server address, Start calls it when it was not called before
+func (s *Server) Listen() error {
+	if !s.Active() || s.listener != nil {
+		return nil
+	}
+	listener, err := net.Listen("tcp", s.httpServer.Addr)
+	if err != nil {
+		return err
+	}
+	s.listener = listener
+	return nil
+}
+
 func (s *Server) stop(ctx context.Context) error {
 	return s.httpServer.Shutdown(ctx)
 }
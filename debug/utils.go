@@ -2,43 +2,47 @@ package debug
 
 import (
 	"encoding/json"
+	"errors"
 	"io"
 	"net/http"
-
-	"github.com/DoomLordor/logger"
 )
 
-type LogLevel struct {
-	ModuleName string `json:"module_name"`
-	LogLevel   string `json:"log_level"`
+type ErrorResponse struct {
+	Error string `json:"error"`
 }
 
-func setLogLevel(w http.ResponseWriter, r *http.Request) {
+func healthCheckHandler(w http.ResponseWriter, _ *http.Request) {
 	w.Header().Add("Content-Type", "application/json")
-	logLevel := &LogLevel{}
-	err := json.NewDecoder(r.Body).Decode(logLevel)
-	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		_, _ = io.WriteString(w, `{"status": "ok"}`)
-		return
-	}
-
-	if logLevel.ModuleName == "" {
-		logLevel.ModuleName = logger.BaseLoggerName
-	}
+	w.WriteHeader(http.StatusOK)
+	_, _ = io.WriteString(w, `{"alive": true}`)
+}
 
-	if logLevel.LogLevel == "" {
-		logLevel.LogLevel = "info"
+func handleWrapper(hf HandlerFunc) http.Handler {
+	f := func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Content-Type", "application/json")
+		res, code, err := hf(r)
+		w.WriteHeader(code)
+		if err != nil {
+			res = ErrorResponse{Error: err.Error()}
+		}
+		if res != nil {
+			_ = json.NewEncoder(w).Encode(res)
+		}
 	}
+	return http.HandlerFunc(f)
+}
 
-	logger.SetLevel(logLevel.ModuleName, logLevel.LogLevel)
-
-	w.WriteHeader(http.StatusOK)
-	_, _ = io.WriteString(w, `{"status": "ok"}`)
+type ErrorStatus struct {
+	Err  error
+	Code int
 }
 
-func healthCheckHandler(w http.ResponseWriter, _ *http.Request) {
-	w.Header().Add("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	_, _ = io.WriteString(w, `{"alive": true}`)
+// StatusCode returns the status of the first error in statuses matching err with errors.Is, 500 otherwise
+func StatusCode(err error, statuses []ErrorStatus) int {
+	for _, status := range statuses {
+		if errors.Is(err, status.Err) {
+			return status.Code
+		}
+	}
+	return http.StatusInternalServerError
 }
@@ -0,0 +1,10 @@
+package faults
+
+import (
+	"time"
+)
+
+type Config struct {
+	Active     bool          `env:"FAULTS" envDefault:"false"`
+	DefaultTTL time.Duration `env:"FAULTS_DEFAULT_TTL" envDefault:"10m"`
+}
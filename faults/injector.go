@@ -0,0 +1,161 @@
+package faults
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/DoomLordor/logger"
+
+	"github.com/DoomLordor/go-apiserver/metrics"
+	"github.com/DoomLordor/go-apiserver/rest"
+)
+
+type Injector struct {
+	config   Config
+	logger   *logger.Logger
+	mu       *sync.Mutex
+	lastId   uint64
+	rules    []*Rule
+	injected *prometheus.CounterVec
+}
+
+func NewInjector(config Config, registerer prometheus.Registerer) (*Injector, error) {
+	injected := prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "fault_injected_requests_total",
+			Help: "Requests affected by fault injection rules",
+		},
+		[]string{"type", "fault"},
+	)
+
+	injected, err := metrics.Register(registerer, injected)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Injector{
+		config:   config,
+		logger:   logger.NewLogger("faults"),
+		mu:       &sync.Mutex{},
+		rules:    make([]*Rule, 0),
+		injected: injected,
+	}, nil
+}
+
+func (i *Injector) Active() bool {
+	return i.config.Active
+}
+
+func (i *Injector) Add(rule Rule) (Rule, error) {
+	err := rule.validate(i.config.DefaultTTL)
+	if err != nil {
+		return Rule{}, err
+	}
+
+	i.mu.Lock()
+	i.lastId++
+	rule.ID = strconv.FormatUint(i.lastId, 10)
+	rule.Hits = 0
+	i.rules = append(i.rules, &rule)
+	i.mu.Unlock()
+
+	i.logger.Warn().
+		Str("id", rule.ID).
+		Str("type", rule.Type).
+		Str("path", rule.Path).
+		Str("method", rule.Method).
+		Float64("percentage", *rule.Percentage).
+		Str("expires_at", rule.ExpiresAt.Format(time.RFC3339)).
+		Msg("Fault rule added")
+	return rule, nil
+}
+
+func (i *Injector) Remove(id string) error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	for index, rule := range i.rules {
+		if rule.ID == id {
+			i.rules = append(i.rules[:index], i.rules[index+1:]...)
+			i.logger.Info().Str("id", id).Msg("Fault rule removed")
+			return nil
+		}
+	}
+	return RuleNotFound
+}
+
+func (i *Injector) Clear() {
+	i.mu.Lock()
+	i.rules = i.rules[:0]
+	i.mu.Unlock()
+	i.logger.Info().Msg("Fault rules cleared")
+}
+
+// List returns active rules in the order they are matched
+func (i *Injector) List() []Rule {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.expire()
+	res := make([]Rule, 0, len(i.rules))
+	for _, rule := range i.rules {
+		res = append(res, *rule)
+	}
+	return res
+}
+
+// match returns a copy of the first rule matching req, rules without a hit are skipped
+func (i *Injector) match(req request) (Rule, bool) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if len(i.rules) == 0 {
+		return Rule{}, false
+	}
+	i.expire()
+	for _, rule := range i.rules {
+		if rule.match(req) {
+			rule.Hits++
+			return *rule, true
+		}
+	}
+	return Rule{}, false
+}
+
+func (i *Injector) expire() {
+	now := time.Now()
+	rules := i.rules[:0]
+	for _, rule := range i.rules {
+		if rule.expired(now) {
+			i.logger.Info().Str("id", rule.ID).Msg("Fault rule expired")
+			continue
+		}
+		rules = append(rules, rule)
+	}
+	i.rules = rules
+}
+
+// delay sleeps for the rule latency, returning early when ctx is done
+func (i *Injector) delay(ctx context.Context, rule Rule, ruleType string) error {
+	if rule.latency == 0 {
+		return nil
+	}
+	i.injected.WithLabelValues(ruleType, "latency").Inc()
+	timer := time.NewTimer(rule.latency)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func user(ctx context.Context) string {
+	if value := ctx.Value(rest.UserKey); value != nil {
+		return fmt.Sprint(value)
+	}
+	return ""
+}
@@ -0,0 +1,117 @@
+package faults
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/DoomLordor/go-apiserver/rest"
+)
+
+const (
+	injectedText = "fault injected"
+	abortedText  = "connection aborted by fault injection"
+)
+
+var (
+	Injected = rest.NewError(http.StatusInternalServerError, "fault_injected", injectedText)
+	Aborted  = rest.NewError(http.StatusBadGateway, "fault_aborted", abortedText)
+)
+
+// Middleware has to run after authorization to match rules by user
+func (i *Injector) Middleware(next http.Handler) http.Handler {
+	f := func(w http.ResponseWriter, r *http.Request) {
+		rule, ok := i.match(request{
+			ruleType: TypeRest,
+			method:   r.Method,
+			path:     rest.PathTemplate(r),
+			header:   r.Header.Get,
+			user:     user(r.Context()),
+		})
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if i.delay(r.Context(), rule, TypeRest) != nil {
+			return
+		}
+
+		switch {
+		case rule.Abort:
+			i.injected.WithLabelValues(TypeRest, "abort").Inc()
+			conn, _, err := http.NewResponseController(w).Hijack()
+			if err != nil {
+				i.logger.Err(err).Str("id", rule.ID).Msg("Connection hijack failed")
+				rest.WriteError(w, r, http.StatusBadGateway, Aborted)
+				return
+			}
+			_ = conn.Close()
+		case rule.Status != 0:
+			i.injected.WithLabelValues(TypeRest, "status").Inc()
+			rest.WriteError(w, r, rule.Status, &rest.Error{Status: rule.Status, Code: Injected.Code, Message: injectedText})
+		default:
+			next.ServeHTTP(w, r)
+		}
+	}
+	return http.HandlerFunc(f)
+}
+
+func (i *Injector) UnaryInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		err := i.inject(ctx, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+func (i *Injector) StreamInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		err := i.inject(ss.Context(), info.FullMethod)
+		if err != nil {
+			return err
+		}
+		return handler(srv, ss)
+	}
+}
+
+func (i *Injector) inject(ctx context.Context, fullMethod string) error {
+	md, _ := metadata.FromIncomingContext(ctx)
+	rule, ok := i.match(request{
+		ruleType: TypeGrpc,
+		path:     fullMethod,
+		header: func(name string) string {
+			values := md.Get(strings.ToLower(name))
+			if len(values) == 0 {
+				return ""
+			}
+			return values[0]
+		},
+		user: user(ctx),
+	})
+	if !ok {
+		return nil
+	}
+
+	err := i.delay(ctx, rule, TypeGrpc)
+	if err != nil {
+		return status.FromContextError(err).Err()
+	}
+
+	switch {
+	case rule.Abort:
+		i.injected.WithLabelValues(TypeGrpc, "abort").Inc()
+		return status.Error(codes.Unavailable, abortedText)
+	case rule.Code != "":
+		i.injected.WithLabelValues(TypeGrpc, "code").Inc()
+		return status.Error(rule.code, injectedText)
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package faults
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"github.com/DoomLordor/go-apiserver/debug"
+)
+
+func (i *Injector) RegistrationDebug() debug.RouteMap {
+	if !i.Active() {
+		return debug.RouteMap{}
+	}
+	return debug.RouteMap{
+		"/faults": {
+			{
+				Methods:     []string{http.MethodGet},
+				Pattern:     "",
+				HandlerFunc: i.list,
+			},
+			{
+				Methods:     []string{http.MethodPost},
+				Pattern:     "",
+				HandlerFunc: i.add,
+			},
+			{
+				Methods:     []string{http.MethodDelete},
+				Pattern:     "",
+				HandlerFunc: i.clear,
+			},
+			{
+				Methods:     []string{http.MethodDelete},
+				Pattern:     "/{id}",
+				HandlerFunc: i.remove,
+			},
+		},
+	}
+}
+
+func (i *Injector) list(_ *http.Request) (any, int, error) {
+	return i.List(), http.StatusOK, nil
+}
+
+func (i *Injector) add(r *http.Request) (any, int, error) {
+	rule := Rule{}
+	err := json.NewDecoder(r.Body).Decode(&rule)
+	if err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+
+	rule, err = i.Add(rule)
+	if err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+	return rule, http.StatusOK, nil
+}
+
+func (i *Injector) clear(_ *http.Request) (any, int, error) {
+	i.Clear()
+	return i.List(), http.StatusOK, nil
+}
+
+func (i *Injector) remove(r *http.Request) (any, int, error) {
+	err := i.Remove(mux.Vars(r)["id"])
+	if errors.Is(err, RuleNotFound) {
+		return nil, http.StatusNotFound, err
+	}
+	return i.List(), http.StatusOK, nil
+}
@@ -0,0 +1,169 @@
+package faults
+
+import (
+	"errors"
+	"math/rand"
+	"net/http"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc/codes"
+)
+
+const (
+	TypeRest = "rest"
+	TypeGrpc = "grpc"
+)
+
+var (
+	InvalidType       = errors.New("invalid rule type")
+	InvalidPercentage = errors.New("percentage must be between 0 and 100")
+	InvalidStatus     = errors.New("invalid status code")
+	InvalidCode       = errors.New("invalid grpc code")
+	EmptyFault        = errors.New("rule has no latency, status, code or abort")
+	RuleNotFound      = errors.New("rule not found")
+)
+
+var grpcCodes = func() map[string]codes.Code {
+	res := make(map[string]codes.Code, 17)
+	for code := codes.OK; code <= codes.Unauthenticated; code++ {
+		res[strings.ToLower(code.String())] = code
+	}
+	return res
+}()
+
+type Rule struct {
+	ID string `json:"id"`
+	// Type is rest, grpc or empty for both, rest rules match WS upgrade requests too
+	Type string `json:"type,omitempty"`
+	// Path is a REST path template or a gRPC full method, a trailing * matches a prefix
+	Path        string `json:"path,omitempty"`
+	Method      string `json:"method,omitempty"`
+	HeaderName  string `json:"header_name,omitempty"`
+	HeaderValue string `json:"header_value,omitempty"`
+	// User is compared with the value stored under rest.UserKey formatted by fmt.Sprint
+	User string `json:"user,omitempty"`
+	// Percentage of matching requests getting the fault, 100 when omitted, 0 pauses the rule
+	Percentage *float64 `json:"percentage"`
+
+	Latency string `json:"latency,omitempty"`
+	Status  int    `json:"status,omitempty"`
+	Code    string `json:"code,omitempty"`
+	Abort   bool   `json:"abort,omitempty"`
+
+	TTL       string    `json:"ttl,omitempty"`
+	ExpiresAt time.Time `json:"expires_at"`
+	Hits      uint64    `json:"hits"`
+
+	latency time.Duration
+	code    codes.Code
+}
+
+func (r *Rule) validate(defaultTTL time.Duration) error {
+	switch r.Type {
+	case "", TypeRest, TypeGrpc:
+	default:
+		return InvalidType
+	}
+
+	if r.Percentage == nil {
+		percentage := 100.0
+		r.Percentage = &percentage
+	}
+	if *r.Percentage < 0 || *r.Percentage > 100 {
+		return InvalidPercentage
+	}
+
+	if r.Latency != "" {
+		latency, err := time.ParseDuration(r.Latency)
+		if err != nil {
+			return err
+		}
+		r.latency = latency
+	}
+
+	if r.Status != 0 && (r.Status < 100 || r.Status > 599) {
+		return InvalidStatus
+	}
+
+	if r.Code != "" {
+		code, ok := grpcCodes[strings.ToLower(r.Code)]
+		if !ok {
+			return InvalidCode
+		}
+		r.code = code
+	}
+
+	if !r.injects(r.Type) {
+		return EmptyFault
+	}
+
+	ttl := defaultTTL
+	if r.TTL != "" {
+		var err error
+		ttl, err = time.ParseDuration(r.TTL)
+		if err != nil {
+			return err
+		}
+	}
+	r.ExpiresAt = time.Now().Add(ttl)
+	r.Method = strings.ToUpper(r.Method)
+	r.HeaderName = http.CanonicalHeaderKey(r.HeaderName)
+	return nil
+}
+
+// injects reports whether the rule has a fault for requests of ruleType, empty for any type
+func (r *Rule) injects(ruleType string) bool {
+	if r.latency != 0 || r.Abort {
+		return true
+	}
+	switch ruleType {
+	case TypeRest:
+		return r.Status != 0
+	case TypeGrpc:
+		return r.Code != ""
+	default:
+		return r.Status != 0 || r.Code != ""
+	}
+}
+
+func (r *Rule) expired(now time.Time) bool {
+	return now.After(r.ExpiresAt)
+}
+
+type request struct {
+	ruleType string
+	method   string
+	path     string
+	header   func(name string) string
+	user     string
+}
+
+func (r *Rule) match(req request) bool {
+	if r.Type != "" && r.Type != req.ruleType {
+		return false
+	}
+	// a status only rule must not hide the next rules from gRPC calls and a code only one from REST requests
+	if !r.injects(req.ruleType) {
+		return false
+	}
+	if r.Method != "" && r.Method != req.method {
+		return false
+	}
+	if r.Path != "" {
+		if prefix, ok := strings.CutSuffix(r.Path, "*"); ok {
+			if !strings.HasPrefix(req.path, prefix) {
+				return false
+			}
+		} else if r.Path != req.path {
+			return false
+		}
+	}
+	if r.HeaderName != "" && req.header(r.HeaderName) != r.HeaderValue {
+		return false
+	}
+	if r.User != "" && r.User != req.user {
+		return false
+	}
+	return rand.Float64()*100 < *r.Percentage
+}
@@ -0,0 +1,5 @@
+package features
+
+type Config struct {
+	File string `env:"FEATURES_FILE" envDefault:""`
+}
@@ -0,0 +1,86 @@
+package features
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"math/rand"
+	"time"
+)
+
+const (
+	TypeBool       = "bool"
+	TypePercentage = "percentage"
+	TypeUsers      = "users"
+)
+
+var (
+	InvalidType       = errors.New("invalid flag type")
+	InvalidPercentage = errors.New("percentage must be between 0 and 100")
+	EmptyName         = errors.New("flag name is empty")
+	FlagNotFound      = errors.New("flag not found")
+)
+
+type Flag struct {
+	Name        string `json:"name"`
+	Type        string `json:"type"`
+	Description string `json:"description,omitempty"`
+	// Enabled is the value of a bool flag and the master switch of the other types
+	Enabled bool `json:"enabled"`
+	// Percentage of users getting a percentage flag, from 0 to 100
+	Percentage float64 `json:"percentage,omitempty"`
+	// Users getting a users flag, compared with the value stored under rest.UserKey formatted by fmt.Sprint
+	Users     []string  `json:"users,omitempty"`
+	UpdatedAt time.Time `json:"updated_at"`
+	users     map[string]struct{}
+}
+
+func (f *Flag) validate() error {
+	if f.Name == "" {
+		return EmptyName
+	}
+	switch f.Type {
+	case "":
+		f.Type = TypeBool
+	case TypeBool, TypeUsers:
+	case TypePercentage:
+		if f.Percentage < 0 || f.Percentage > 100 {
+			return InvalidPercentage
+		}
+	default:
+		return InvalidType
+	}
+
+	f.users = make(map[string]struct{}, len(f.Users))
+	for _, user := range f.Users {
+		f.users[user] = struct{}{}
+	}
+	return nil
+}
+
+// enabled evaluates the flag for the user, user is empty for anonymous requests
+func (f *Flag) enabled(user string) bool {
+	if !f.Enabled {
+		return false
+	}
+
+	switch f.Type {
+	case TypePercentage:
+		return bucket(f.Name, user) < f.Percentage
+	case TypeUsers:
+		_, ok := f.users[user]
+		return ok
+	default:
+		return true
+	}
+}
+
+// bucket places the user in [0, 100), anonymous users get a random bucket
+func bucket(name, user string) float64 {
+	if user == "" {
+		return rand.Float64() * 100
+	}
+	hash := fnv.New32a()
+	_, _ = fmt.Fprintf(hash, "%s:%s", name, user)
+	return float64(hash.Sum32()%10000) / 100
+}
@@ -0,0 +1,71 @@
+package features
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"github.com/DoomLordor/go-apiserver/debug"
+)
+
+func (s *Store) RegistrationDebug() debug.RouteMap {
+	return debug.RouteMap{
+		"/features": {
+			{
+				Methods:     []string{http.MethodGet},
+				Pattern:     "",
+				HandlerFunc: s.list,
+			},
+			{
+				Methods:     []string{http.MethodPut},
+				Pattern:     "",
+				HandlerFunc: s.set,
+			},
+			{
+				Methods:     []string{http.MethodPatch},
+				Pattern:     "/{name}",
+				HandlerFunc: s.update,
+			},
+		},
+	}
+}
+
+func (s *Store) list(_ *http.Request) (any, int, error) {
+	return s.List(), http.StatusOK, nil
+}
+
+func (s *Store) set(r *http.Request) (any, int, error) {
+	flag := Flag{}
+	err := json.NewDecoder(r.Body).Decode(&flag)
+	if err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+
+	flag, err = s.Set(flag)
+	if err != nil {
+		return nil, debug.StatusCode(err, statuses), err
+	}
+	return flag, http.StatusOK, nil
+}
+
+func (s *Store) update(r *http.Request) (any, int, error) {
+	update := Update{}
+	err := json.NewDecoder(r.Body).Decode(&update)
+	if err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+
+	flag, err := s.Update(mux.Vars(r)["name"], update)
+	if err != nil {
+		return nil, debug.StatusCode(err, statuses), err
+	}
+	return flag, http.StatusOK, nil
+}
+
+var statuses = []debug.ErrorStatus{
+	{Err: FlagNotFound, Code: http.StatusNotFound},
+	{Err: InvalidType, Code: http.StatusBadRequest},
+	{Err: InvalidPercentage, Code: http.StatusBadRequest},
+	{Err: EmptyName, Code: http.StatusBadRequest},
+}
@@ -0,0 +1,206 @@
+package features
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/DoomLordor/logger"
+
+	"github.com/DoomLordor/go-apiserver/filestore"
+	"github.com/DoomLordor/go-apiserver/rest"
+)
+
+type storeKey struct{}
+
+type Update struct {
+	Enabled    *bool    `json:"enabled"`
+	Percentage *float64 `json:"percentage"`
+	Users      []string `json:"users"`
+}
+
+type Store struct {
+	config Config
+	logger *logger.Logger
+	mu     *sync.RWMutex
+	flags  map[string]*Flag
+	file   *filestore.File[Flag]
+}
+
+func NewStore(config Config) (*Store, error) {
+	s := &Store{
+		config: config,
+		logger: logger.NewLogger("features"),
+		mu:     &sync.RWMutex{},
+		flags:  make(map[string]*Flag, 10),
+		file:   filestore.New[Flag](config.File),
+	}
+
+	err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Register adds default flags, flags loaded from the file keep their state
+func (s *Store) Register(flags ...Flag) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, flag := range flags {
+		flag := flag
+		if _, ok := s.flags[flag.Name]; ok {
+			continue
+		}
+		err := flag.validate()
+		if err != nil {
+			return fmt.Errorf("flag %q: %w", flag.Name, err)
+		}
+		flag.UpdatedAt = time.Now()
+		s.flags[flag.Name] = &flag
+	}
+	return nil
+}
+
+func (s *Store) Set(flag Flag) (Flag, error) {
+	err := flag.validate()
+	if err != nil {
+		return flag, err
+	}
+	flag.UpdatedAt = time.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return flag, s.apply(flag)
+}
+
+func (s *Store) Update(name string, update Update) (Flag, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current, ok := s.flags[name]
+	if !ok {
+		return Flag{}, FlagNotFound
+	}
+
+	flag := *current
+	if update.Enabled != nil {
+		flag.Enabled = *update.Enabled
+	}
+	if update.Percentage != nil {
+		flag.Percentage = *update.Percentage
+	}
+	if update.Users != nil {
+		flag.Users = update.Users
+	}
+
+	err := flag.validate()
+	if err != nil {
+		return flag, err
+	}
+	flag.UpdatedAt = time.Now()
+	return flag, s.apply(flag)
+}
+
+// apply saves the flags with flag before serving it so that a failed write changes nothing,
+// it must be called with the write lock held
+func (s *Store) apply(flag Flag) error {
+	flags := maps.Clone(s.flags)
+	flags[flag.Name] = &flag
+	err := s.file.Save(sorted(flags))
+	if err != nil {
+		return err
+	}
+
+	s.flags = flags
+	s.logChange(flag)
+	return nil
+}
+
+func (s *Store) Get(name string) (Flag, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	flag, ok := s.flags[name]
+	if !ok {
+		return Flag{}, false
+	}
+	return *flag, true
+}
+
+func (s *Store) List() []Flag {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return sorted(s.flags)
+}
+
+// Enabled evaluates the flag for the user stored in ctx, unknown flags are disabled
+func (s *Store) Enabled(ctx context.Context, name string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	flag, ok := s.flags[name]
+	if !ok {
+		return false
+	}
+	user := ""
+	if value := ctx.Value(rest.UserKey); value != nil {
+		user = fmt.Sprint(value)
+	}
+	return flag.enabled(user)
+}
+
+func (s *Store) logChange(flag Flag) {
+	s.logger.Info().
+		Str("flag", flag.Name).
+		Str("type", flag.Type).
+		Bool("enabled", flag.Enabled).
+		Float64("percentage", flag.Percentage).
+		Strs("users", flag.Users).
+		Msg("Flag changed")
+}
+
+func (s *Store) load() error {
+	flags, err := s.file.Load()
+	if err != nil {
+		return err
+	}
+
+	for _, flag := range flags {
+		flag := flag
+		if err = flag.validate(); err != nil {
+			s.logger.Warn().Str("flag", flag.Name).Str("warning", err.Error()).Msg("Skip flag")
+			continue
+		}
+		s.flags[flag.Name] = &flag
+	}
+	return nil
+}
+
+func sorted(flags map[string]*Flag) []Flag {
+	res := make([]Flag, 0, len(flags))
+	for _, flag := range flags {
+		res = append(res, *flag)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Name < res[j].Name
+	})
+	return res
+}
+
+func FromContext(ctx context.Context) *Store {
+	store, _ := ctx.Value(storeKey{}).(*Store)
+	return store
+}
+
+// Enabled evaluates the flag with the store carried by ctx
+func Enabled(ctx context.Context, name string) bool {
+	store := FromContext(ctx)
+	if store == nil {
+		return false
+	}
+	return store.Enabled(ctx, name)
+}
@@ -0,0 +1,57 @@
+package filestore
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+)
+
+// File persists a list of records as indented JSON, the file is replaced atomically on save
+type File[T any] struct {
+	path string
+}
+
+func New[T any](path string) *File[T] {
+	return &File[T]{path: path}
+}
+
+// Load returns the saved records, none when the path is empty or the file does not exist yet
+func (f *File[T]) Load() ([]T, error) {
+	if f.path == "" {
+		return nil, nil
+	}
+
+	data, err := os.ReadFile(f.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	records := make([]T, 0, 10)
+	err = json.Unmarshal(data, &records)
+	if err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+// Save replaces the file content with records, it does nothing when the path is empty
+func (f *File[T]) Save(records []T) error {
+	if f.path == "" {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(records, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp := f.path + ".tmp"
+	err = os.WriteFile(tmp, data, 0o644)
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp, f.path)
+}
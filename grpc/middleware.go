@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"runtime/debug"
+	"sync/atomic"
 	"time"
 
 	"go.opentelemetry.io/otel"
@@ -21,10 +22,14 @@ import (
 	"github.com/grpc-ecosystem/go-grpc-middleware/v2/interceptors/recovery"
 )
 
+// RequestIdKey holds the request ID in the context, it is the key used by REST
+const RequestIdKey = "requestId"
+
 type Middlewares struct {
 	logger           *logger.Logger
 	metricsCollector *grpcprom.ServerMetrics
 	tracer           trace.Tracer
+	requestId        *atomic.Uint64
 }
 
 func NewMiddlewares(logger *logger.Logger, tracer trace.Tracer) *Middlewares {
@@ -32,6 +37,7 @@ func NewMiddlewares(logger *logger.Logger, tracer trace.Tracer) *Middlewares {
 		logger:           logger,
 		metricsCollector: grpcprom.NewServerMetrics(),
 		tracer:           tracer,
+		requestId:        &atomic.Uint64{},
 	}
 }
 
@@ -46,16 +52,18 @@ func (m *Middlewares) RecoveryMiddleware() recovery.Option {
 
 func (m *Middlewares) TimeMiddleware() grpc.UnaryServerInterceptor {
 	f := func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		requestId := m.requestId.Add(1)
 		start := time.Now().UnixMilli()
-		m.logger.Info().Str("full_method", info.FullMethod).Msg("Start")
+		m.logger.Info().Str("full_method", info.FullMethod).Uint64("requestId", requestId).Msg("Start")
 
-		resp, err := handler(ctx, req)
+		resp, err := handler(context.WithValue(ctx, RequestIdKey, requestId), req)
 		statusErr, _ := status.FromError(err)
 		end := time.Now().UnixMilli() - start
 		m.logger.Info().
 			Str("full_method", info.FullMethod).
 			Uint64("code", uint64(statusErr.Code())).
 			Int64("response_time", end).
+			Uint64("requestId", requestId).
 			Msg("End")
 
 		return resp, err
@@ -78,7 +86,8 @@ func (m *Middlewares) LoggingMiddleware() grpc.UnaryServerInterceptor {
 
 func (m *Middlewares) LoggingStreamMiddleware() grpc.StreamServerInterceptor {
 	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
-		err := handler(srv, ss)
+		ctx := context.WithValue(ss.Context(), RequestIdKey, m.requestId.Add(1))
+		err := handler(srv, &serverStream{ServerStream: ss, ctx: ctx})
 
 		if err != nil {
 			m.logging(info.FullMethod, err)
@@ -138,3 +147,12 @@ func (m *Middlewares) TracingMiddleware() grpc.UnaryServerInterceptor {
 		return resp, err
 	}
 }
+
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
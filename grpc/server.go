@@ -1,7 +1,9 @@
 package grpc
 
 import (
+	"fmt"
 	"net"
+	"sort"
 
 	grpcprom "github.com/grpc-ecosystem/go-grpc-middleware/providers/prometheus"
 	"github.com/grpc-ecosystem/go-grpc-middleware/v2/interceptors/recovery"
@@ -12,6 +14,8 @@ import (
 	"google.golang.org/grpc/reflection"
 
 	"github.com/DoomLordor/logger"
+
+	"github.com/DoomLordor/go-apiserver/metrics"
 )
 
 type Grps interface {
@@ -23,6 +27,8 @@ type Server struct {
 	logger     *logger.Logger
 	grpcServer *grpc.Server
 	listener   net.Listener
+	unaryUse   []grpc.UnaryServerInterceptor
+	streamUse  []grpc.StreamServerInterceptor
 }
 
 func NewServer(config Config) *Server {
@@ -33,7 +39,15 @@ func NewServer(config Config) *Server {
 	}
 }
 
-func (s *Server) Configuration(grps []Grps, tracer trace.Tracer) error {
+func (s *Server) UseUnary(interceptors ...grpc.UnaryServerInterceptor) {
+	s.unaryUse = append(s.unaryUse, interceptors...)
+}
+
+func (s *Server) UseStream(interceptors ...grpc.StreamServerInterceptor) {
+	s.streamUse = append(s.streamUse, interceptors...)
+}
+
+func (s *Server) Configuration(grps []Grps, tracer trace.Tracer, registerer prometheus.Registerer) error {
 	listener, err := net.Listen("tcp", s.config.BindAddress())
 	if err != nil {
 		return err
@@ -41,27 +55,30 @@ func (s *Server) Configuration(grps []Grps, tracer trace.Tracer) error {
 
 	s.listener = listener
 
-	metricsCollector := grpcprom.NewServerMetrics()
-	err = prometheus.Register(metricsCollector)
-	if err != nil && err.Error() != "duplicate metrics collector registration attempted" {
+	metricsCollector, err := metrics.Register(registerer, grpcprom.NewServerMetrics())
+	if err != nil {
 		return err
 	}
 
 	middlewares := NewMiddlewares(logger.NewLogger("middlewares-grpc"), tracer)
 
+	unary := []grpc.UnaryServerInterceptor{
+		metricsCollector.UnaryServerInterceptor(),
+		recovery.UnaryServerInterceptor(middlewares.RecoveryMiddleware()),
+		middlewares.TracingMiddleware(),
+		middlewares.TimeMiddleware(),
+		middlewares.LoggingMiddleware(),
+	}
+
+	stream := []grpc.StreamServerInterceptor{
+		metricsCollector.StreamServerInterceptor(),
+		recovery.StreamServerInterceptor(middlewares.RecoveryMiddleware()),
+		middlewares.LoggingStreamMiddleware(),
+	}
+
 	s.grpcServer = grpc.NewServer(
-		grpc.ChainUnaryInterceptor(
-			metricsCollector.UnaryServerInterceptor(),
-			recovery.UnaryServerInterceptor(middlewares.RecoveryMiddleware()),
-			middlewares.TracingMiddleware(),
-			middlewares.TimeMiddleware(),
-			middlewares.LoggingMiddleware(),
-		),
-		grpc.ChainStreamInterceptor(
-			metricsCollector.StreamServerInterceptor(),
-			recovery.StreamServerInterceptor(middlewares.RecoveryMiddleware()),
-			middlewares.LoggingStreamMiddleware(),
-		),
+		grpc.ChainUnaryInterceptor(append(unary, s.unaryUse...)...),
+		grpc.ChainStreamInterceptor(append(stream, s.streamUse...)...),
 	)
 
 	for _, imp := range grps {
@@ -73,6 +90,37 @@ func (s *Server) Configuration(grps []Grps, tracer trace.Tracer) error {
 	return nil
 }
 
+func (s *Server) Services() []ServiceInfo {
+	if s.grpcServer == nil {
+		return nil
+	}
+
+	info := s.grpcServer.GetServiceInfo()
+	res := make([]ServiceInfo, 0, len(info))
+	for name, service := range info {
+		methods := make([]MethodInfo, 0, len(service.Methods))
+		for _, method := range service.Methods {
+			methods = append(methods, MethodInfo{
+				Name:       method.Name,
+				FullMethod: fmt.Sprintf("/%s/%s", name, method.Name),
+				StreamKind: streamKind(method.IsClientStream, method.IsServerStream),
+			})
+		}
+		sort.Slice(methods, func(i, j int) bool {
+			return methods[i].Name < methods[j].Name
+		})
+		res = append(res, ServiceInfo{
+			Name:     name,
+			Methods:  methods,
+			Metadata: service.Metadata,
+		})
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Name < res[j].Name
+	})
+	return res
+}
+
 func (s *Server) Start() {
 	if !s.Active() {
 		return
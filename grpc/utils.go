@@ -1,9 +1,21 @@
 package grpc
 
 import (
+	"context"
+
+	"go.opentelemetry.io/otel"
 	"google.golang.org/grpc/metadata"
 )
 
+// ExtractTrace returns ctx with the remote span context propagated in the incoming metadata
+func ExtractTrace(ctx context.Context) context.Context {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ctx
+	}
+	return otel.GetTextMapPropagator().Extract(ctx, metadataCarrier(md))
+}
+
 // metadataCarrier type for using MD as open telemetry TextMapCarrier
 type metadataCarrier metadata.MD
 
@@ -25,3 +37,35 @@ func (mc metadataCarrier) Get(key string) string {
 func (mc metadataCarrier) Set(key string, value string) {
 	metadata.MD(mc).Append(key, value)
 }
+
+const (
+	StreamKindUnary        = "unary"
+	StreamKindClientStream = "client_stream"
+	StreamKindServerStream = "server_stream"
+	StreamKindBidiStream   = "bidi_stream"
+)
+
+type MethodInfo struct {
+	Name       string `json:"name"`
+	FullMethod string `json:"full_method"`
+	StreamKind string `json:"stream_kind"`
+}
+
+type ServiceInfo struct {
+	Name     string       `json:"name"`
+	Methods  []MethodInfo `json:"methods"`
+	Metadata any          `json:"metadata,omitempty"`
+}
+
+func streamKind(clientStream, serverStream bool) string {
+	switch {
+	case clientStream && serverStream:
+		return StreamKindBidiStream
+	case clientStream:
+		return StreamKindClientStream
+	case serverStream:
+		return StreamKindServerStream
+	default:
+		return StreamKindUnary
+	}
+}
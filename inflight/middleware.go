@@ -0,0 +1,94 @@
+package inflight
+
+import (
+	"context"
+	"net/http"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"google.golang.org/grpc"
+
+	grpcserver "github.com/DoomLordor/go-apiserver/grpc"
+	"github.com/DoomLordor/go-apiserver/rest"
+)
+
+func (t *Tracker) RestMiddleware(next http.Handler) http.Handler {
+	return t.httpMiddleware(TypeRest, next)
+}
+
+func (t *Tracker) WsMiddleware(next http.Handler) http.Handler {
+	return t.httpMiddleware(TypeWs, next)
+}
+
+func (t *Tracker) httpMiddleware(entryType string, next http.Handler) http.Handler {
+	f := func(w http.ResponseWriter, r *http.Request) {
+		requestId, _ := r.Context().Value(rest.RequestIdKey).(uint64)
+		entry := &Entry{
+			Type:      entryType,
+			Method:    r.Method,
+			Path:      rest.PathTemplate(r),
+			URL:       r.RequestURI,
+			RequestId: requestId,
+		}
+
+		parent := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+		entry.TraceId = traceId(parent)
+
+		ctx, done := t.start(r.Context(), entry)
+		defer done()
+		next.ServeHTTP(w, r.WithContext(ctx))
+	}
+	return http.HandlerFunc(f)
+}
+
+// UserMiddleware records the authenticated user, it must run after the token check
+func (t *Tracker) UserMiddleware(next http.Handler) http.Handler {
+	f := func(w http.ResponseWriter, r *http.Request) {
+		t.annotate(r.Context(), r.Context().Value(rest.UserKey))
+		next.ServeHTTP(w, r)
+	}
+	return http.HandlerFunc(f)
+}
+
+func (t *Tracker) UnaryInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		requestId, _ := ctx.Value(grpcserver.RequestIdKey).(uint64)
+		entry := &Entry{
+			Type:      TypeGrpc,
+			Method:    MethodUnary,
+			Path:      info.FullMethod,
+			User:      ctx.Value(rest.UserKey),
+			RequestId: requestId,
+		}
+		ctx, done := t.start(ctx, entry)
+		defer done()
+		return handler(ctx, req)
+	}
+}
+
+func (t *Tracker) StreamInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		requestId, _ := ss.Context().Value(grpcserver.RequestIdKey).(uint64)
+		entry := &Entry{
+			Type:      TypeGrpc,
+			Method:    MethodStream,
+			Path:      info.FullMethod,
+			User:      ss.Context().Value(rest.UserKey),
+			RequestId: requestId,
+			// streams have no server span, only the remote one
+			TraceId: traceId(grpcserver.ExtractTrace(ss.Context())),
+		}
+		ctx, done := t.start(ss.Context(), entry)
+		defer done()
+		return handler(srv, &serverStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
@@ -0,0 +1,45 @@
+package inflight
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+
+	"github.com/DoomLordor/go-apiserver/debug"
+)
+
+func (t *Tracker) RegistrationDebug() debug.RouteMap {
+	return debug.RouteMap{
+		"/inflight": {
+			{
+				Methods:     []string{http.MethodGet},
+				Pattern:     "",
+				HandlerFunc: t.list,
+			},
+			{
+				Methods:     []string{http.MethodPost},
+				Pattern:     "/{id:[0-9]+}/cancel",
+				HandlerFunc: t.cancel,
+			},
+		},
+	}
+}
+
+func (t *Tracker) list(_ *http.Request) (any, int, error) {
+	return t.List(), http.StatusOK, nil
+}
+
+func (t *Tracker) cancel(r *http.Request) (any, int, error) {
+	id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+
+	entry, err := t.Cancel(id)
+	if errors.Is(err, EntryNotFound) {
+		return nil, http.StatusNotFound, err
+	}
+	return entry, http.StatusOK, nil
+}
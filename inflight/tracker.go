@@ -0,0 +1,178 @@
+package inflight
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/DoomLordor/logger"
+)
+
+const (
+	TypeRest = "rest"
+	TypeWs   = "ws"
+	TypeGrpc = "grpc"
+
+	MethodUnary  = "unary"
+	MethodStream = "stream"
+)
+
+var EntryNotFound = errors.New("request not found")
+
+type Entry struct {
+	ID        uint64    `json:"id"`
+	Type      string    `json:"type"`
+	Method    string    `json:"method"`
+	Path      string    `json:"path"`
+	URL       string    `json:"url,omitempty"`
+	StartedAt time.Time `json:"started_at"`
+	Age       string    `json:"age"`
+	User      any       `json:"user,omitempty"`
+	RequestId uint64    `json:"request_id,omitempty"`
+	TraceId   string    `json:"trace_id,omitempty"`
+	Cancelled bool      `json:"cancelled"`
+	cancel    context.CancelFunc
+}
+
+type entryKey struct{}
+
+type Tracker struct {
+	logger  *logger.Logger
+	lastId  *atomic.Uint64
+	mu      *sync.RWMutex
+	entries map[uint64]*Entry
+}
+
+func NewTracker() *Tracker {
+	return &Tracker{
+		logger:  logger.NewLogger("inflight"),
+		lastId:  &atomic.Uint64{},
+		mu:      &sync.RWMutex{},
+		entries: make(map[uint64]*Entry, 100),
+	}
+}
+
+func (t *Tracker) start(ctx context.Context, entry *Entry) (context.Context, func()) {
+	ctx, cancel := context.WithCancel(ctx)
+	entry.ID = t.lastId.Add(1)
+	entry.StartedAt = time.Now()
+	if entry.TraceId == "" {
+		entry.TraceId = traceId(ctx)
+	}
+	entry.cancel = cancel
+
+	t.mu.Lock()
+	t.entries[entry.ID] = entry
+	t.mu.Unlock()
+
+	done := func() {
+		t.mu.Lock()
+		delete(t.entries, entry.ID)
+		t.mu.Unlock()
+		cancel()
+	}
+	return context.WithValue(ctx, entryKey{}, entry), done
+}
+
+func (t *Tracker) annotate(ctx context.Context, user any) {
+	entry, ok := ctx.Value(entryKey{}).(*Entry)
+	if !ok {
+		return
+	}
+	t.mu.Lock()
+	if user != nil {
+		entry.User = user
+	}
+	if entry.TraceId == "" {
+		entry.TraceId = traceId(ctx)
+	}
+	t.mu.Unlock()
+}
+
+// Trace sets the trace ID of the request from its server span, it is a REST span hook
+func (t *Tracker) Trace(ctx context.Context) {
+	entry, ok := ctx.Value(entryKey{}).(*Entry)
+	if !ok {
+		return
+	}
+	id := traceId(ctx)
+	if id == "" {
+		return
+	}
+	t.mu.Lock()
+	entry.TraceId = id
+	t.mu.Unlock()
+}
+
+// List returns in-flight requests, the oldest first
+func (t *Tracker) List() []Entry {
+	now := time.Now()
+	t.mu.RLock()
+	res := make([]Entry, 0, len(t.entries))
+	for _, entry := range t.entries {
+		e := *entry
+		e.Age = now.Sub(e.StartedAt).String()
+		res = append(res, e)
+	}
+	t.mu.RUnlock()
+
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].StartedAt.Before(res[j].StartedAt)
+	})
+	return res
+}
+
+func (t *Tracker) Count() int {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return len(t.entries)
+}
+
+// CountBy counts requests of entryType, an empty method matches any method
+func (t *Tracker) CountBy(entryType, method string) int {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	count := 0
+	for _, entry := range t.entries {
+		if entry.Type == entryType && (method == "" || entry.Method == method) {
+			count++
+		}
+	}
+	return count
+}
+
+func (t *Tracker) Cancel(id uint64) (Entry, error) {
+	t.mu.Lock()
+	entry, ok := t.entries[id]
+	if !ok {
+		t.mu.Unlock()
+		return Entry{}, EntryNotFound
+	}
+	entry.Cancelled = true
+	e := *entry
+	t.mu.Unlock()
+
+	entry.cancel()
+	t.logger.Warn().
+		Uint64("id", e.ID).
+		Str("type", e.Type).
+		Str("method", e.Method).
+		Str("path", e.Path).
+		Msg("Request cancelled")
+
+	e.Age = time.Since(e.StartedAt).String()
+	return e, nil
+}
+
+func traceId(ctx context.Context) string {
+	spanContext := trace.SpanContextFromContext(ctx)
+	if !spanContext.HasTraceID() {
+		return ""
+	}
+	return spanContext.TraceID().String()
+}
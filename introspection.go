@@ -0,0 +1,45 @@
+package apiserver
+
+import (
+	"net/http"
+
+	"github.com/DoomLordor/go-apiserver/debug"
+	"github.com/DoomLordor/go-apiserver/grpc"
+	"github.com/DoomLordor/go-apiserver/rest"
+)
+
+type RoutesResponse struct {
+	Rest []rest.RouteInfo   `json:"rest"`
+	Grpc []grpc.ServiceInfo `json:"grpc"`
+}
+
+type introspection struct {
+	httpServer *rest.Server
+	grpcServer *grpc.Server
+}
+
+func (i *introspection) RegistrationDebug() debug.RouteMap {
+	return debug.RouteMap{
+		"/routes": {
+			{
+				Methods:     []string{http.MethodGet},
+				Pattern:     "",
+				HandlerFunc: i.routes,
+			},
+		},
+	}
+}
+
+func (i *introspection) routes(_ *http.Request) (any, int, error) {
+	res := RoutesResponse{
+		Rest: []rest.RouteInfo{},
+		Grpc: []grpc.ServiceInfo{},
+	}
+	if i.httpServer.Active() {
+		res.Rest = i.httpServer.Routes()
+	}
+	if i.grpcServer.Active() {
+		res.Grpc = i.grpcServer.Services()
+	}
+	return res, http.StatusOK, nil
+}
@@ -0,0 +1,5 @@
+package killswitch
+
+type Config struct {
+	File string `env:"KILL_SWITCH_FILE" envDefault:""`
+}
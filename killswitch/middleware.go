@@ -0,0 +1,56 @@
+package killswitch
+
+import (
+	"context"
+	"net/http"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/DoomLordor/go-apiserver/rest"
+)
+
+const disabledText = "endpoint temporarily disabled"
+
+var EndpointDisabled = rest.NewError(http.StatusServiceUnavailable, "endpoint_disabled", disabledText)
+
+func (r *Registry) RestMiddleware(next http.Handler) http.Handler {
+	f := func(w http.ResponseWriter, req *http.Request) {
+		if r.Disabled(TypeRest, req.Method, rest.PathTemplate(req)) {
+			rest.WriteError(w, req, http.StatusServiceUnavailable, EndpointDisabled)
+			return
+		}
+		next.ServeHTTP(w, req)
+	}
+	return http.HandlerFunc(f)
+}
+
+func (r *Registry) WsMiddleware(next http.Handler) http.Handler {
+	f := func(w http.ResponseWriter, req *http.Request) {
+		if r.Disabled(TypeWs, "", rest.PathTemplate(req)) {
+			rest.WriteError(w, req, http.StatusServiceUnavailable, EndpointDisabled)
+			return
+		}
+		next.ServeHTTP(w, req)
+	}
+	return http.HandlerFunc(f)
+}
+
+func (r *Registry) UnaryInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if r.Disabled(TypeGrpc, "", info.FullMethod) {
+			return nil, status.Error(codes.Unavailable, disabledText)
+		}
+		return handler(ctx, req)
+	}
+}
+
+func (r *Registry) StreamInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if r.Disabled(TypeGrpc, "", info.FullMethod) {
+			return status.Error(codes.Unavailable, disabledText)
+		}
+		return handler(srv, ss)
+	}
+}
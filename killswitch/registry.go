@@ -0,0 +1,197 @@
+package killswitch
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/DoomLordor/logger"
+
+	"github.com/DoomLordor/go-apiserver/filestore"
+	"github.com/DoomLordor/go-apiserver/metrics"
+)
+
+const (
+	TypeRest = "rest"
+	TypeWs   = "ws"
+	TypeGrpc = "grpc"
+)
+
+var (
+	InvalidType   = errors.New("invalid switch type")
+	EmptyTarget   = errors.New("switch target is empty")
+	EmptyMethod   = errors.New("switch method is empty")
+	SwitchMissing = errors.New("switch not found")
+)
+
+type Switch struct {
+	Type       string    `json:"type"`
+	Target     string    `json:"target"`
+	Method     string    `json:"method,omitempty"`
+	Reason     string    `json:"reason,omitempty"`
+	DisabledAt time.Time `json:"disabled_at"`
+}
+
+func (s *Switch) key() string {
+	return key(s.Type, s.Method, s.Target)
+}
+
+func (s *Switch) validate() error {
+	switch s.Type {
+	case TypeRest:
+		s.Method = strings.ToUpper(s.Method)
+		if s.Method == "" {
+			return EmptyMethod
+		}
+	case TypeWs, TypeGrpc:
+		s.Method = ""
+	default:
+		return InvalidType
+	}
+	if s.Target == "" {
+		return EmptyTarget
+	}
+	return nil
+}
+
+func key(switchType, method, target string) string {
+	return fmt.Sprintf("%s %s %s", switchType, method, target)
+}
+
+type Registry struct {
+	config   Config
+	logger   *logger.Logger
+	mu       *sync.RWMutex
+	switches map[string]Switch
+	file     *filestore.File[Switch]
+	disabled *prometheus.GaugeVec
+}
+
+func NewRegistry(config Config, registerer prometheus.Registerer) (*Registry, error) {
+	disabled := prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "disabled_endpoints",
+			Help: "Endpoints disabled by kill switches",
+		},
+		[]string{"type", "target", "method"},
+	)
+
+	disabled, err := metrics.Register(registerer, disabled)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &Registry{
+		config:   config,
+		logger:   logger.NewLogger("kill-switch"),
+		mu:       &sync.RWMutex{},
+		switches: make(map[string]Switch, 10),
+		file:     filestore.New[Switch](config.File),
+		disabled: disabled,
+	}
+
+	err = r.load()
+	if err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+func (r *Registry) Disable(s Switch) (Switch, error) {
+	err := s.validate()
+	if err != nil {
+		return s, err
+	}
+	s.DisabledAt = time.Now()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	switches := maps.Clone(r.switches)
+	switches[s.key()] = s
+	err = r.save(switches)
+	if err != nil {
+		return s, err
+	}
+
+	r.switches = switches
+	r.disabled.WithLabelValues(s.Type, s.Target, s.Method).Set(1)
+	r.logger.Warn().Str("type", s.Type).Str("target", s.Target).Str("method", s.Method).Str("reason", s.Reason).Msg("Disabled")
+	return s, nil
+}
+
+func (r *Registry) Enable(s Switch) error {
+	err := s.validate()
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.switches[s.key()]; !ok {
+		return SwitchMissing
+	}
+	switches := maps.Clone(r.switches)
+	delete(switches, s.key())
+	err = r.save(switches)
+	if err != nil {
+		return err
+	}
+
+	r.switches = switches
+	r.disabled.DeleteLabelValues(s.Type, s.Target, s.Method)
+	r.logger.Warn().Str("type", s.Type).Str("target", s.Target).Str("method", s.Method).Msg("Enabled")
+	return nil
+}
+
+func (r *Registry) Disabled(switchType, method, target string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	_, ok := r.switches[key(switchType, method, target)]
+	return ok
+}
+
+func (r *Registry) List() []Switch {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return sorted(r.switches)
+}
+
+func (r *Registry) load() error {
+	switches, err := r.file.Load()
+	if err != nil {
+		return err
+	}
+
+	for _, s := range switches {
+		if err = s.validate(); err != nil {
+			r.logger.Warn().Str("type", s.Type).Str("target", s.Target).Str("warning", err.Error()).Msg("Skip switch")
+			continue
+		}
+		r.switches[s.key()] = s
+		r.disabled.WithLabelValues(s.Type, s.Target, s.Method).Set(1)
+	}
+	return nil
+}
+
+// save persists switches before they replace the current ones so that a failed write changes nothing
+func (r *Registry) save(switches map[string]Switch) error {
+	return r.file.Save(sorted(switches))
+}
+
+func sorted(switches map[string]Switch) []Switch {
+	res := make([]Switch, 0, len(switches))
+	for _, s := range switches {
+		res = append(res, s)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].key() < res[j].key()
+	})
+	return res
+}
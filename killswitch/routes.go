@@ -0,0 +1,69 @@
+package killswitch
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/DoomLordor/go-apiserver/debug"
+)
+
+func (r *Registry) RegistrationDebug() debug.RouteMap {
+	return debug.RouteMap{
+		"/switches": {
+			{
+				Methods:     []string{http.MethodGet},
+				Pattern:     "",
+				HandlerFunc: r.list,
+			},
+			{
+				Methods:     []string{http.MethodPost},
+				Pattern:     "/disable",
+				HandlerFunc: r.disable,
+			},
+			{
+				Methods:     []string{http.MethodPost},
+				Pattern:     "/enable",
+				HandlerFunc: r.enable,
+			},
+		},
+	}
+}
+
+func (r *Registry) list(_ *http.Request) (any, int, error) {
+	return r.List(), http.StatusOK, nil
+}
+
+func (r *Registry) disable(req *http.Request) (any, int, error) {
+	s := Switch{}
+	err := json.NewDecoder(req.Body).Decode(&s)
+	if err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+
+	s, err = r.Disable(s)
+	if err != nil {
+		return nil, debug.StatusCode(err, statuses), err
+	}
+	return s, http.StatusOK, nil
+}
+
+func (r *Registry) enable(req *http.Request) (any, int, error) {
+	s := Switch{}
+	err := json.NewDecoder(req.Body).Decode(&s)
+	if err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+
+	err = r.Enable(s)
+	if err != nil {
+		return nil, debug.StatusCode(err, statuses), err
+	}
+	return r.List(), http.StatusOK, nil
+}
+
+var statuses = []debug.ErrorStatus{
+	{Err: InvalidType, Code: http.StatusBadRequest},
+	{Err: EmptyTarget, Code: http.StatusBadRequest},
+	{Err: EmptyMethod, Code: http.StatusBadRequest},
+	{Err: SwitchMissing, Code: http.StatusNotFound},
+}
@@ -0,0 +1,19 @@
+package apiserver
+
+import (
+	"github.com/DoomLordor/go-apiserver/debug"
+)
+
+// SetLogBuffer serves buffer on the debug /logs endpoint, it must be called before Configuration.
+// The application tees its logger into the buffer before creating any logger, e.g.
+//
+//	logs := debug.NewLogBuffer(1000)
+//	err := logger.InitLogger(io.MultiWriter(os.Stdout, logs), config)
+//	...
+//	s := apiserver.NewServer(serverConfig)
+//	s.SetLogBuffer(logs)
+//
+// The buffer parses zerolog JSON lines, so LogJson must stay on for the writer it is teed to
+func (s *APIServer) SetLogBuffer(buffer *debug.LogBuffer) {
+	s.logs = buffer
+}
@@ -0,0 +1,13 @@
+package maintenance
+
+import (
+	"time"
+)
+
+type Config struct {
+	Enabled    bool          `env:"MAINTENANCE" envDefault:"false"`
+	Message    string        `env:"MAINTENANCE_MESSAGE" envDefault:"service is under maintenance"`
+	RetryAfter time.Duration `env:"MAINTENANCE_RETRY_AFTER" envDefault:"60s"`
+	Allow      []string      `env:"MAINTENANCE_ALLOW" envSeparator:","`
+	Signal     bool          `env:"MAINTENANCE_SIGNAL" envDefault:"true"`
+}
@@ -0,0 +1,43 @@
+package maintenance
+
+import (
+	"context"
+	"net/http"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/DoomLordor/go-apiserver/rest"
+)
+
+func (m *Mode) Middleware(next http.Handler) http.Handler {
+	f := func(w http.ResponseWriter, r *http.Request) {
+		if m.Blocked(rest.PathTemplate(r)) {
+			w.Header().Set("Retry-After", m.retryAfter())
+			err := &rest.Error{Status: http.StatusServiceUnavailable, Code: "maintenance", Message: m.Message(), Retryable: true, Err: InMaintenance}
+			rest.WriteError(w, r, http.StatusServiceUnavailable, err)
+			return
+		}
+		next.ServeHTTP(w, r)
+	}
+	return http.HandlerFunc(f)
+}
+
+func (m *Mode) UnaryInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if m.Blocked(info.FullMethod) {
+			return nil, status.Error(codes.Unavailable, m.Message())
+		}
+		return handler(ctx, req)
+	}
+}
+
+func (m *Mode) StreamInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if m.Blocked(info.FullMethod) {
+			return status.Error(codes.Unavailable, m.Message())
+		}
+		return handler(srv, ss)
+	}
+}
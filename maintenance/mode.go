@@ -0,0 +1,181 @@
+package maintenance
+
+import (
+	"errors"
+	"os"
+	"os/signal"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/DoomLordor/logger"
+
+	"github.com/DoomLordor/go-apiserver/metrics"
+)
+
+var InMaintenance = errors.New("service in maintenance mode")
+
+type Status struct {
+	Enabled    bool      `json:"enabled"`
+	Message    string    `json:"message"`
+	RetryAfter string    `json:"retry_after"`
+	Since      time.Time `json:"since"`
+	Allow      []string  `json:"allow"`
+}
+
+type Mode struct {
+	config  Config
+	logger  *logger.Logger
+	mu      *sync.RWMutex
+	enabled bool
+	message string
+	since   time.Time
+	allow   map[string]struct{}
+	gauge   prometheus.Gauge
+	signals chan os.Signal
+}
+
+func NewMode(config Config, registerer prometheus.Registerer) (*Mode, error) {
+	gauge := prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "maintenance_mode",
+			Help: "Whether the server is in maintenance mode",
+		},
+	)
+
+	gauge, err := metrics.Register(registerer, gauge)
+	if err != nil {
+		return nil, err
+	}
+
+	allow := make(map[string]struct{}, len(config.Allow))
+	for _, a := range config.Allow {
+		allow[a] = struct{}{}
+	}
+
+	m := &Mode{
+		config:  config,
+		logger:  logger.NewLogger("maintenance"),
+		mu:      &sync.RWMutex{},
+		message: config.Message,
+		allow:   allow,
+		gauge:   gauge,
+	}
+
+	if config.Enabled {
+		m.Enable("")
+	}
+
+	return m, nil
+}
+
+func (m *Mode) Enable(message string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if message == "" {
+		message = m.config.Message
+	}
+	m.message = message
+	if !m.enabled {
+		m.enabled = true
+		m.since = time.Now()
+	}
+	m.gauge.Set(1)
+	m.logger.Warn().Str("reason", message).Msg("Maintenance enabled")
+}
+
+func (m *Mode) Disable() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.enabled = false
+	m.since = time.Time{}
+	m.message = m.config.Message
+	m.gauge.Set(0)
+	m.logger.Warn().Msg("Maintenance disabled")
+}
+
+func (m *Mode) Toggle() {
+	if m.Enabled() {
+		m.Disable()
+	} else {
+		m.Enable("")
+	}
+}
+
+func (m *Mode) Enabled() bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.enabled
+}
+
+// Blocked reports whether a request to target must be refused,
+// target being a REST/WS path template or a gRPC full method
+func (m *Mode) Blocked(target string) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if !m.enabled {
+		return false
+	}
+	_, ok := m.allow[target]
+	return !ok
+}
+
+func (m *Mode) Message() string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.message
+}
+
+func (m *Mode) Status() Status {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	allow := make([]string, 0, len(m.config.Allow))
+	allow = append(allow, m.config.Allow...)
+	return Status{
+		Enabled:    m.enabled,
+		Message:    m.message,
+		RetryAfter: m.config.RetryAfter.String(),
+		Since:      m.since,
+		Allow:      allow,
+	}
+}
+
+func (m *Mode) ReadinessCheck() error {
+	if m.Enabled() {
+		return InMaintenance
+	}
+	return nil
+}
+
+func (m *Mode) Start() {
+	if !m.config.Signal || toggleSignal == nil {
+		return
+	}
+
+	signals := make(chan os.Signal, 1)
+	m.signals = signals
+	signal.Notify(signals, toggleSignal)
+	go func() {
+		for range signals {
+			m.Toggle()
+		}
+	}()
+}
+
+func (m *Mode) Stop() {
+	if m.signals == nil {
+		return
+	}
+	signal.Stop(m.signals)
+	close(m.signals)
+	m.signals = nil
+}
+
+func (m *Mode) retryAfter() string {
+	return strconv.Itoa(int(m.config.RetryAfter.Seconds()))
+}
@@ -0,0 +1,49 @@
+package maintenance
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/DoomLordor/go-apiserver/debug"
+)
+
+type SetRequest struct {
+	Enabled bool   `json:"enabled"`
+	Message string `json:"message"`
+}
+
+func (m *Mode) RegistrationDebug() debug.RouteMap {
+	return debug.RouteMap{
+		"/maintenance": {
+			{
+				Methods:     []string{http.MethodGet},
+				Pattern:     "",
+				HandlerFunc: m.status,
+			},
+			{
+				Methods:     []string{http.MethodPost},
+				Pattern:     "",
+				HandlerFunc: m.set,
+			},
+		},
+	}
+}
+
+func (m *Mode) status(_ *http.Request) (any, int, error) {
+	return m.Status(), http.StatusOK, nil
+}
+
+func (m *Mode) set(r *http.Request) (any, int, error) {
+	req := SetRequest{}
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+
+	if req.Enabled {
+		m.Enable(req.Message)
+	} else {
+		m.Disable()
+	}
+	return m.Status(), http.StatusOK, nil
+}
@@ -0,0 +1,10 @@
+//go:build !windows
+
+package maintenance
+
+import (
+	"os"
+	"syscall"
+)
+
+var toggleSignal os.Signal = syscall.SIGUSR1
@@ -0,0 +1,9 @@
+//go:build windows
+
+package maintenance
+
+import (
+	"os"
+)
+
+var toggleSignal os.Signal
@@ -0,0 +1,22 @@
+package memguard
+
+import (
+	"time"
+)
+
+const (
+	SourceHeap = "heap"
+	SourceRSS  = "rss"
+)
+
+type Config struct {
+	Active          bool          `env:"MEMORY_GUARD" envDefault:"false"`
+	Interval        time.Duration `env:"MEMORY_GUARD_INTERVAL" envDefault:"5s"`
+	Source          string        `env:"MEMORY_GUARD_SOURCE" envDefault:"heap"`
+	SoftLimit       uint64        `env:"MEMORY_GUARD_SOFT_LIMIT" envDefault:"0"`
+	HardLimit       uint64        `env:"MEMORY_GUARD_HARD_LIMIT" envDefault:"0"`
+	Shed            bool          `env:"MEMORY_GUARD_SHED" envDefault:"false"`
+	ProfileDir      string        `env:"MEMORY_GUARD_PROFILE_DIR" envDefault:""`
+	ProfileCooldown time.Duration `env:"MEMORY_GUARD_PROFILE_COOLDOWN" envDefault:"10m"`
+	TopSites        int           `env:"MEMORY_GUARD_TOP_SITES" envDefault:"5"`
+}
@@ -0,0 +1,301 @@
+package memguard
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime/pprof"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/DoomLordor/logger"
+
+	"github.com/DoomLordor/go-apiserver/metrics"
+)
+
+const (
+	StateOk   = "ok"
+	StateSoft = "soft"
+	StateHard = "hard"
+)
+
+var (
+	OverHardLimit   = errors.New("memory usage over the hard limit")
+	InvalidInterval = errors.New("memory guard interval must be positive")
+)
+
+type Status struct {
+	Active      bool      `json:"active"`
+	Source      string    `json:"source"`
+	State       string    `json:"state"`
+	Bytes       uint64    `json:"bytes"`
+	SoftLimit   uint64    `json:"soft_limit"`
+	HardLimit   uint64    `json:"hard_limit"`
+	Shedding    bool      `json:"shedding"`
+	LastProfile string    `json:"last_profile,omitempty"`
+	CheckedAt   time.Time `json:"checked_at"`
+}
+
+type Guard struct {
+	config      Config
+	logger      *logger.Logger
+	mu          *sync.RWMutex
+	state       string
+	bytes       uint64
+	checkedAt   time.Time
+	lastProfile string
+	profiledAt  time.Time
+	done        chan struct{}
+	wg          *sync.WaitGroup
+
+	usage  prometheus.Gauge
+	level  prometheus.Gauge
+	events *prometheus.CounterVec
+	shed   *prometheus.CounterVec
+}
+
+func NewGuard(config Config, registerer prometheus.Registerer) (*Guard, error) {
+	if config.Active && config.Interval <= 0 {
+		return nil, InvalidInterval
+	}
+
+	usage := prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "memory_guard_bytes",
+		Help: "Memory usage watched by the memory guard",
+	})
+	level := prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "memory_guard_state",
+		Help: "Memory guard state: 0 ok, 1 over the soft limit, 2 over the hard limit",
+	})
+	events := prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "memory_guard_events_total",
+			Help: "Total number of memory guard events",
+		},
+		[]string{"event"},
+	)
+	shed := prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "memory_guard_shed_total",
+			Help: "Total number of requests rejected over the hard memory limit",
+		},
+		[]string{"protocol"},
+	)
+
+	usage, err := metrics.Register(registerer, usage)
+	if err != nil {
+		return nil, err
+	}
+
+	level, err = metrics.Register(registerer, level)
+	if err != nil {
+		return nil, err
+	}
+
+	events, err = metrics.Register(registerer, events)
+	if err != nil {
+		return nil, err
+	}
+
+	shed, err = metrics.Register(registerer, shed)
+	if err != nil {
+		return nil, err
+	}
+
+	if config.Source != SourceRSS {
+		config.Source = SourceHeap
+	}
+
+	return &Guard{
+		config: config,
+		logger: logger.NewLogger("memory-guard"),
+		mu:     &sync.RWMutex{},
+		state:  StateOk,
+		wg:     &sync.WaitGroup{},
+		usage:  usage,
+		level:  level,
+		events: events,
+		shed:   shed,
+	}, nil
+}
+
+func (g *Guard) Active() bool {
+	return g.config.Active
+}
+
+func (g *Guard) Start() {
+	if !g.Active() {
+		return
+	}
+
+	g.done = make(chan struct{})
+	g.wg.Add(1)
+	go func() {
+		defer g.wg.Done()
+		ticker := time.NewTicker(g.config.Interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-g.done:
+				return
+			case <-ticker.C:
+				g.check()
+			}
+		}
+	}()
+	g.logger.Info().Msg("Memory guard start")
+}
+
+func (g *Guard) Stop() {
+	if g.done == nil {
+		return
+	}
+	close(g.done)
+	g.wg.Wait()
+	g.done = nil
+}
+
+func (g *Guard) Status() Status {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return Status{
+		Active:      g.Active(),
+		Source:      g.config.Source,
+		State:       g.state,
+		Bytes:       g.bytes,
+		SoftLimit:   g.config.SoftLimit,
+		HardLimit:   g.config.HardLimit,
+		Shedding:    g.config.Shed && g.state == StateHard,
+		LastProfile: g.lastProfile,
+		CheckedAt:   g.checkedAt,
+	}
+}
+
+func (g *Guard) ReadinessCheck() error {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	if g.state == StateHard {
+		return OverHardLimit
+	}
+	return nil
+}
+
+func (g *Guard) shedding() bool {
+	if !g.config.Shed {
+		return false
+	}
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.state == StateHard
+}
+
+func (g *Guard) check() {
+	bytes := heapBytes()
+	if g.config.Source == SourceRSS {
+		bytes = rssBytes()
+	}
+	g.usage.Set(float64(bytes))
+
+	state := StateOk
+	switch {
+	case g.config.HardLimit > 0 && bytes >= g.config.HardLimit:
+		state = StateHard
+	case g.config.SoftLimit > 0 && bytes >= g.config.SoftLimit:
+		state = StateSoft
+	}
+
+	g.mu.Lock()
+	previous := g.state
+	g.state = state
+	g.bytes = bytes
+	g.checkedAt = time.Now()
+	g.mu.Unlock()
+
+	switch state {
+	case StateHard:
+		g.level.Set(2)
+	case StateSoft:
+		g.level.Set(1)
+	default:
+		g.level.Set(0)
+	}
+
+	if state == previous {
+		return
+	}
+
+	if state == StateOk {
+		g.logger.Info().Uint64("bytes", bytes).Str("previous", previous).Msg("Memory usage back to normal")
+		return
+	}
+
+	g.events.WithLabelValues(state).Inc()
+	sites := topAllocationSites(g.config.TopSites)
+	event := g.logger.Warn().
+		Str("state", state).
+		Str("source", g.config.Source).
+		Uint64("bytes", bytes).
+		Uint64("soft_limit", g.config.SoftLimit).
+		Uint64("hard_limit", g.config.HardLimit).
+		Bool("shed", g.config.Shed && state == StateHard)
+	for i, site := range sites {
+		event = event.Str(fmt.Sprintf("site_%d", i), fmt.Sprintf("%s %s %d", site.Function, site.File, site.InUseBytes))
+	}
+	event.Msg("Memory limit crossed")
+
+	if previous == StateOk {
+		g.writeProfile()
+	}
+}
+
+func (g *Guard) writeProfile() {
+	if g.config.ProfileDir == "" {
+		return
+	}
+
+	g.mu.RLock()
+	profiledAt := g.profiledAt
+	g.mu.RUnlock()
+	if !profiledAt.IsZero() && time.Since(profiledAt) < g.config.ProfileCooldown {
+		return
+	}
+
+	now := time.Now()
+	path := filepath.Join(g.config.ProfileDir, fmt.Sprintf("heap-%s.pprof", now.Format("20060102-150405")))
+	err := g.saveProfile(path)
+	if err != nil {
+		g.logger.Err(err).Str("path", path).Msg("Heap profile")
+		return
+	}
+
+	g.mu.Lock()
+	g.lastProfile = path
+	g.profiledAt = now
+	g.mu.Unlock()
+
+	g.events.WithLabelValues("profile").Inc()
+	g.logger.Warn().Str("path", path).Msg("Heap profile written")
+}
+
+func (g *Guard) saveProfile(path string) error {
+	err := os.MkdirAll(g.config.ProfileDir, 0o755)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	err = pprof.WriteHeapProfile(file)
+	if err != nil {
+		_ = file.Close()
+		return err
+	}
+	return file.Close()
+}
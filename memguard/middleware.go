@@ -0,0 +1,49 @@
+package memguard
+
+import (
+	"context"
+	"net/http"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/DoomLordor/go-apiserver/rest"
+)
+
+const shedText = "server is overloaded"
+
+var Overloaded = &rest.Error{Status: http.StatusServiceUnavailable, Code: "overloaded", Message: shedText, Retryable: true, Err: OverHardLimit}
+
+func (g *Guard) Middleware(next http.Handler) http.Handler {
+	f := func(w http.ResponseWriter, r *http.Request) {
+		if g.shedding() {
+			g.shed.WithLabelValues("rest").Inc()
+			w.Header().Set("Retry-After", "10")
+			rest.WriteError(w, r, http.StatusServiceUnavailable, Overloaded)
+			return
+		}
+		next.ServeHTTP(w, r)
+	}
+	return http.HandlerFunc(f)
+}
+
+func (g *Guard) UnaryInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if g.shedding() {
+			g.shed.WithLabelValues("grpc").Inc()
+			return nil, status.Error(codes.ResourceExhausted, shedText)
+		}
+		return handler(ctx, req)
+	}
+}
+
+func (g *Guard) StreamInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if g.shedding() {
+			g.shed.WithLabelValues("grpc").Inc()
+			return status.Error(codes.ResourceExhausted, shedText)
+		}
+		return handler(srv, ss)
+	}
+}
@@ -0,0 +1,32 @@
+package memguard
+
+import (
+	"net/http"
+
+	"github.com/DoomLordor/go-apiserver/debug"
+)
+
+func (g *Guard) RegistrationDebug() debug.RouteMap {
+	return debug.RouteMap{
+		"/memory": {
+			{
+				Methods:     []string{http.MethodGet},
+				Pattern:     "",
+				HandlerFunc: g.status,
+			},
+			{
+				Methods:     []string{http.MethodGet},
+				Pattern:     "/sites",
+				HandlerFunc: g.sites,
+			},
+		},
+	}
+}
+
+func (g *Guard) status(_ *http.Request) (any, int, error) {
+	return g.Status(), http.StatusOK, nil
+}
+
+func (g *Guard) sites(_ *http.Request) (any, int, error) {
+	return topAllocationSites(g.config.TopSites), http.StatusOK, nil
+}
@@ -0,0 +1,82 @@
+package memguard
+
+import (
+	"os"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type AllocationSite struct {
+	Function     string `json:"function"`
+	File         string `json:"file"`
+	InUseBytes   int64  `json:"in_use_bytes"`
+	InUseObjects int64  `json:"in_use_objects"`
+}
+
+func heapBytes() uint64 {
+	stats := runtime.MemStats{}
+	runtime.ReadMemStats(&stats)
+	return stats.HeapAlloc
+}
+
+// rssBytes reads the resident set size from /proc, 0 when not available
+func rssBytes() uint64 {
+	data, err := os.ReadFile("/proc/self/statm")
+	if err != nil {
+		return 0
+	}
+	fields := strings.Fields(string(data))
+	if len(fields) < 2 {
+		return 0
+	}
+	pages, err := strconv.ParseUint(fields[1], 10, 64)
+	if err != nil {
+		return 0
+	}
+	return pages * uint64(os.Getpagesize())
+}
+
+// topAllocationSites aggregates the sampled heap profile by the allocating function
+func topAllocationSites(limit int) []AllocationSite {
+	records := make([]runtime.MemProfileRecord, 0, 1024)
+	n, ok := runtime.MemProfile(nil, true)
+	for !ok {
+		records = make([]runtime.MemProfileRecord, n+50)
+		n, ok = runtime.MemProfile(records, true)
+	}
+	records = records[:n]
+
+	sites := make(map[string]*AllocationSite, len(records))
+	for _, record := range records {
+		frames := runtime.CallersFrames(record.Stack())
+		frame, more := frames.Next()
+		for more && strings.HasPrefix(frame.Function, "runtime.") {
+			frame, more = frames.Next()
+		}
+
+		site, found := sites[frame.Function]
+		if !found {
+			site = &AllocationSite{
+				Function: frame.Function,
+				File:     frame.File + ":" + strconv.Itoa(frame.Line),
+			}
+			sites[frame.Function] = site
+		}
+		site.InUseBytes += record.InUseBytes()
+		site.InUseObjects += record.InUseObjects()
+	}
+
+	res := make([]AllocationSite, 0, len(sites))
+	for _, site := range sites {
+		res = append(res, *site)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].InUseBytes > res[j].InUseBytes
+	})
+	if limit > 0 && len(res) > limit {
+		res = res[:limit]
+	}
+	return res
+}
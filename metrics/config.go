@@ -0,0 +1,8 @@
+package metrics
+
+type Config struct {
+	Namespace        string `env:"METRICS_NAMESPACE" envDefault:""`
+	GoCollector      bool   `env:"METRICS_GO_COLLECTOR" envDefault:"true"`
+	ProcessCollector bool   `env:"METRICS_PROCESS_COLLECTOR" envDefault:"true"`
+	IncludeDefault   bool   `env:"METRICS_INCLUDE_DEFAULT" envDefault:"false"`
+}
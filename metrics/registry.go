@@ -0,0 +1,69 @@
+package metrics
+
+import (
+	"errors"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+)
+
+type Registry struct {
+	registry   *prometheus.Registry
+	registerer prometheus.Registerer
+	gatherer   prometheus.Gatherer
+}
+
+func NewRegistry(config Config) *Registry {
+	registry := prometheus.NewRegistry()
+	if config.GoCollector {
+		registry.MustRegister(collectors.NewGoCollector())
+	}
+	if config.ProcessCollector {
+		registry.MustRegister(collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}))
+	}
+
+	var registerer prometheus.Registerer = registry
+	if config.Namespace != "" {
+		registerer = prometheus.WrapRegistererWithPrefix(config.Namespace+"_", registry)
+	}
+
+	var gatherer prometheus.Gatherer = registry
+	if config.IncludeDefault {
+		gatherer = prometheus.Gatherers{registry, prometheus.DefaultGatherer}
+	}
+
+	return &Registry{
+		registry:   registry,
+		registerer: registerer,
+		gatherer:   gatherer,
+	}
+}
+
+// Registerer registers collectors with the configured namespace
+func (r *Registry) Registerer() prometheus.Registerer {
+	return r.registerer
+}
+
+func (r *Registry) Gatherer() prometheus.Gatherer {
+	return r.gatherer
+}
+
+// Register registers the collector and returns it,
+// if an equal collector is already registered the existing one is returned instead
+func Register[T prometheus.Collector](registerer prometheus.Registerer, collector T) (T, error) {
+	err := registerer.Register(collector)
+	if err == nil {
+		return collector, nil
+	}
+
+	alreadyRegistered := prometheus.AlreadyRegisteredError{}
+	if !errors.As(err, &alreadyRegistered) {
+		return collector, err
+	}
+
+	existing, ok := alreadyRegistered.ExistingCollector.(T)
+	if !ok {
+		return collector, err
+	}
+	return existing, nil
+}
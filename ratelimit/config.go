@@ -0,0 +1,29 @@
+package ratelimit
+
+import (
+	"time"
+)
+
+// Config is the global rule applied to every REST request, per-route rules are set with Route
+type Config struct {
+	Active    bool          `env:"RATE_LIMIT" envDefault:"false"`
+	Algorithm string        `env:"RATE_LIMIT_ALGORITHM" envDefault:"token_bucket"`
+	Limit     int           `env:"RATE_LIMIT_LIMIT" envDefault:"100"`
+	Window    time.Duration `env:"RATE_LIMIT_WINDOW" envDefault:"1m"`
+	// Burst is the token bucket capacity, Limit is used when it is zero
+	Burst int    `env:"RATE_LIMIT_BURST" envDefault:"0"`
+	Key   string `env:"RATE_LIMIT_KEY" envDefault:"ip"`
+	// TrustProxy takes the client IP from the first X-Forwarded-For address
+	TrustProxy bool `env:"RATE_LIMIT_TRUST_PROXY" envDefault:"false"`
+}
+
+func (c Config) rule() Rule {
+	return Rule{
+		Name:      "global",
+		Algorithm: c.Algorithm,
+		Limit:     c.Limit,
+		Window:    c.Window,
+		Burst:     c.Burst,
+		Key:       c.Key,
+	}
+}
@@ -0,0 +1,103 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/DoomLordor/logger"
+
+	"github.com/DoomLordor/go-apiserver/metrics"
+)
+
+type limiterKey struct{}
+
+type Status struct {
+	Active bool   `json:"active"`
+	Rule   Rule   `json:"rule"`
+	Store  string `json:"store"`
+}
+
+type Limiter struct {
+	config  Config
+	logger  *logger.Logger
+	rule    Rule
+	store   Store
+	limited *prometheus.CounterVec
+}
+
+func NewLimiter(config Config, registerer prometheus.Registerer) (*Limiter, error) {
+	rule := config.rule()
+	if config.Active {
+		err := rule.validate()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	limited := prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "rate_limited_requests_total",
+			Help: "Total number of requests rejected by rate limits",
+		},
+		[]string{"rule"},
+	)
+
+	limited, err := metrics.Register(registerer, limited)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Limiter{
+		config:  config,
+		logger:  logger.NewLogger("ratelimit"),
+		rule:    rule,
+		store:   NewMemoryStore(),
+		limited: limited,
+	}, nil
+}
+
+// Active reports whether the global rule is applied, route rules work regardless
+func (l *Limiter) Active() bool {
+	return l.config.Active
+}
+
+// SetStore replaces the in-memory store, it must be called before the server starts
+func (l *Limiter) SetStore(store Store) {
+	l.store = store
+}
+
+// SetKeyFunc makes the global rule count requests by a custom key, it is applied after authentication
+func (l *Limiter) SetKeyFunc(keyFunc KeyFunc) {
+	l.rule.KeyFunc = keyFunc
+}
+
+func (l *Limiter) Status() Status {
+	return Status{
+		Active: l.Active(),
+		Rule:   l.rule,
+		Store:  fmt.Sprintf("%T", l.store),
+	}
+}
+
+// Allow counts one request of key against rule
+func (l *Limiter) Allow(ctx context.Context, rule *Rule, key string) (Result, error) {
+	now := time.Now()
+	if rule.Algorithm == AlgorithmSlidingWindow {
+		return l.store.SlidingWindow(ctx, key, rule.Limit, rule.Window, now)
+	}
+	rate := float64(rule.Limit) / rule.Window.Seconds()
+	return l.store.TokenBucket(ctx, key, rule.capacity(), rate, now)
+}
+
+// afterAuth reports whether the global rule needs the authenticated user
+func (l *Limiter) afterAuth() bool {
+	return l.rule.Key == KeyUser || l.rule.KeyFunc != nil
+}
+
+func FromContext(ctx context.Context) *Limiter {
+	limiter, _ := ctx.Value(limiterKey{}).(*Limiter)
+	return limiter
+}
@@ -0,0 +1,97 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/DoomLordor/go-apiserver/rest"
+)
+
+// Middleware makes the limiter available to Route rules and applies the global rule keyed by IP
+func (l *Limiter) Middleware(next http.Handler) http.Handler {
+	f := func(w http.ResponseWriter, r *http.Request) {
+		r = r.WithContext(context.WithValue(r.Context(), limiterKey{}, l))
+		if l.Active() && !l.afterAuth() {
+			l.serve(w, r, &l.rule, next)
+			return
+		}
+		next.ServeHTTP(w, r)
+	}
+	return http.HandlerFunc(f)
+}
+
+// UserMiddleware applies the global rule keyed by user or a custom key after authentication
+func (l *Limiter) UserMiddleware(next http.Handler) http.Handler {
+	f := func(w http.ResponseWriter, r *http.Request) {
+		if l.Active() && l.afterAuth() {
+			l.serve(w, r, &l.rule, next)
+			return
+		}
+		next.ServeHTTP(w, r)
+	}
+	return http.HandlerFunc(f)
+}
+
+// Route limits a single route with the limiter of the server, it panics on an invalid rule.
+// Rules keyed by user must be added to RouteMiddlewares.AfterAuth
+func Route(rule Rule) mux.MiddlewareFunc {
+	err := rule.validate()
+	if err != nil {
+		panic(err)
+	}
+	return func(next http.Handler) http.Handler {
+		f := func(w http.ResponseWriter, r *http.Request) {
+			limiter := FromContext(r.Context())
+			if limiter == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+			limiter.serve(w, r, &rule, next)
+		}
+		return http.HandlerFunc(f)
+	}
+}
+
+func (l *Limiter) serve(w http.ResponseWriter, r *http.Request, rule *Rule, next http.Handler) {
+	key := rule.key(r, l.config.TrustProxy)
+	if key == "" {
+		next.ServeHTTP(w, r)
+		return
+	}
+	name := rule.Name
+	if name == "" {
+		name = rest.PathTemplate(r)
+	}
+
+	res, err := l.Allow(r.Context(), rule, name+"|"+key)
+	if err != nil {
+		// a failing store must not take the service down with it
+		l.logger.Err(err).Str("rule", name).Msg("Rate limit store failed")
+		next.ServeHTTP(w, r)
+		return
+	}
+
+	header := w.Header()
+	header.Set("RateLimit-Limit", strconv.Itoa(res.Limit))
+	header.Set("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+	header.Set("RateLimit-Reset", ceilSeconds(res.Reset))
+	header.Set("RateLimit-Policy", rule.policy())
+	if res.Allowed {
+		next.ServeHTTP(w, r)
+		return
+	}
+
+	l.limited.WithLabelValues(name).Inc()
+	l.logger.Warn().Str("rule", name).Str("key", key).Str("url", r.RequestURI).Msg("Rate limited")
+	header.Set("Retry-After", ceilSeconds(max(res.RetryAfter, time.Second)))
+	rest.WriteError(w, r, http.StatusTooManyRequests, Limited)
+}
+
+func ceilSeconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
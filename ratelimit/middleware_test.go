@@ -0,0 +1,130 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+type call struct {
+	algorithm string
+	key       string
+	limit     int
+	rate      float64
+	window    time.Duration
+}
+
+type fakeStore struct {
+	calls  []call
+	result Result
+	err    error
+}
+
+func (s *fakeStore) TokenBucket(_ context.Context, key string, capacity int, rate float64, _ time.Time) (Result, error) {
+	s.calls = append(s.calls, call{algorithm: AlgorithmTokenBucket, key: key, limit: capacity, rate: rate})
+	return s.result, s.err
+}
+
+func (s *fakeStore) SlidingWindow(_ context.Context, key string, limit int, window time.Duration, _ time.Time) (Result, error) {
+	s.calls = append(s.calls, call{algorithm: AlgorithmSlidingWindow, key: key, limit: limit, window: window})
+	return s.result, s.err
+}
+
+func newTestLimiter(t *testing.T, config Config, store Store) *Limiter {
+	t.Helper()
+	limiter, err := NewLimiter(config, prometheus.NewRegistry())
+	if err != nil {
+		t.Fatal(err)
+	}
+	limiter.SetStore(store)
+	return limiter
+}
+
+func serve(limiter *Limiter) (*httptest.ResponseRecorder, bool) {
+	served := false
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		served = true
+		w.WriteHeader(http.StatusNoContent)
+	})
+	r := httptest.NewRequest(http.MethodGet, "/items", nil)
+	r.RemoteAddr = "192.0.2.1:1234"
+	w := httptest.NewRecorder()
+	limiter.Middleware(next).ServeHTTP(w, r)
+	return w, served
+}
+
+func TestMiddlewareUsesStore(t *testing.T) {
+	tests := []struct {
+		name   string
+		config Config
+		want   call
+	}{
+		{
+			name:   "token bucket",
+			config: Config{Active: true, Algorithm: AlgorithmTokenBucket, Limit: 60, Window: time.Minute, Burst: 10},
+			want:   call{algorithm: AlgorithmTokenBucket, key: "global|ip:192.0.2.1", limit: 10, rate: 1},
+		},
+		{
+			name:   "sliding window",
+			config: Config{Active: true, Algorithm: AlgorithmSlidingWindow, Limit: 5, Window: time.Second},
+			want:   call{algorithm: AlgorithmSlidingWindow, key: "global|ip:192.0.2.1", limit: 5, window: time.Second},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &fakeStore{result: Result{Allowed: true, Limit: tt.want.limit, Remaining: 3, Reset: 1500 * time.Millisecond}}
+			w, served := serve(newTestLimiter(t, tt.config, store))
+
+			if !served {
+				t.Fatal("allowed request was not served")
+			}
+			if len(store.calls) != 1 || store.calls[0] != tt.want {
+				t.Fatalf("got store calls %+v, want %+v", store.calls, tt.want)
+			}
+			if got := w.Header().Get("RateLimit-Remaining"); got != "3" {
+				t.Errorf("got RateLimit-Remaining %q, want 3", got)
+			}
+			if got := w.Header().Get("RateLimit-Reset"); got != "2" {
+				t.Errorf("got RateLimit-Reset %q, want 2", got)
+			}
+		})
+	}
+}
+
+func TestMiddlewareRejects(t *testing.T) {
+	store := &fakeStore{result: Result{Limit: 5, Reset: 10 * time.Second, RetryAfter: 1200 * time.Millisecond}}
+	config := Config{Active: true, Algorithm: AlgorithmSlidingWindow, Limit: 5, Window: 10 * time.Second}
+	w, served := serve(newTestLimiter(t, config, store))
+
+	if served {
+		t.Fatal("rejected request was served")
+	}
+	if w.Code != http.StatusTooManyRequests {
+		t.Errorf("got status %d, want 429", w.Code)
+	}
+	if got := w.Header().Get("Retry-After"); got != "2" {
+		t.Errorf("got Retry-After %q, want 2", got)
+	}
+	if got := w.Header().Get("RateLimit-Policy"); got != "5;w=10" {
+		t.Errorf("got RateLimit-Policy %q, want 5;w=10", got)
+	}
+	if got := w.Body.String(); got != "{\"error\":\"Too Many Requests\"}\n" {
+		t.Errorf("got body %q", got)
+	}
+}
+
+func TestMiddlewareStoreFailure(t *testing.T) {
+	store := &fakeStore{err: errors.New("store down")}
+	config := Config{Active: true, Limit: 5, Window: time.Second}
+	w, served := serve(newTestLimiter(t, config, store))
+
+	if !served || w.Code != http.StatusNoContent {
+		t.Errorf("got served %v status %d, a failing store must not reject requests", served, w.Code)
+	}
+}
@@ -0,0 +1,23 @@
+package ratelimit
+
+import (
+	"net/http"
+
+	"github.com/DoomLordor/go-apiserver/debug"
+)
+
+func (l *Limiter) RegistrationDebug() debug.RouteMap {
+	return debug.RouteMap{
+		"/ratelimit": {
+			{
+				Methods:     []string{http.MethodGet},
+				Pattern:     "",
+				HandlerFunc: l.status,
+			},
+		},
+	}
+}
+
+func (l *Limiter) status(_ *http.Request) (any, int, error) {
+	return l.Status(), http.StatusOK, nil
+}
@@ -0,0 +1,106 @@
+package ratelimit
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/DoomLordor/go-apiserver/rest"
+)
+
+const (
+	AlgorithmTokenBucket   = "token_bucket"
+	AlgorithmSlidingWindow = "sliding_window"
+
+	KeyIP   = "ip"
+	KeyUser = "user"
+)
+
+var (
+	InvalidAlgorithm = errors.New("invalid rate limit algorithm")
+	InvalidKey       = errors.New("invalid rate limit key")
+	InvalidLimit     = errors.New("rate limit and window must be positive")
+	Limited          = &rest.Error{Status: http.StatusTooManyRequests, Code: "rate_limited", Message: http.StatusText(http.StatusTooManyRequests), Retryable: true}
+)
+
+// KeyFunc returns the key requests are counted by, an empty key is not limited
+type KeyFunc func(r *http.Request) string
+
+type Rule struct {
+	// Name separates counters of rules, the route path template is used when empty
+	Name      string        `json:"name"`
+	Algorithm string        `json:"algorithm"`
+	Limit     int           `json:"limit"`
+	Window    time.Duration `json:"window"`
+	Burst     int           `json:"burst,omitempty"`
+	// Key is ip or user, KeyFunc replaces it when set
+	Key     string  `json:"key"`
+	KeyFunc KeyFunc `json:"-"`
+}
+
+func (r *Rule) validate() error {
+	switch r.Algorithm {
+	case "":
+		r.Algorithm = AlgorithmTokenBucket
+	case AlgorithmTokenBucket, AlgorithmSlidingWindow:
+	default:
+		return fmt.Errorf("%w: %q", InvalidAlgorithm, r.Algorithm)
+	}
+
+	if r.KeyFunc == nil {
+		switch r.Key {
+		case "":
+			r.Key = KeyIP
+		case KeyIP, KeyUser:
+		default:
+			return fmt.Errorf("%w: %q", InvalidKey, r.Key)
+		}
+	}
+
+	if r.Limit <= 0 || r.Window <= 0 || r.Burst < 0 {
+		return InvalidLimit
+	}
+	return nil
+}
+
+func (r *Rule) capacity() int {
+	if r.Burst > 0 {
+		return r.Burst
+	}
+	return r.Limit
+}
+
+// policy is the RateLimit-Policy header value
+func (r *Rule) policy() string {
+	return fmt.Sprintf("%d;w=%d", r.Limit, int(r.Window.Seconds()))
+}
+
+func (r *Rule) key(req *http.Request, trustProxy bool) string {
+	if r.KeyFunc != nil {
+		return r.KeyFunc(req)
+	}
+	if r.Key == KeyUser {
+		if user := req.Context().Value(rest.UserKey); user != nil {
+			return "user:" + fmt.Sprint(user)
+		}
+		return ""
+	}
+	return "ip:" + clientIP(req, trustProxy)
+}
+
+func clientIP(r *http.Request, trustProxy bool) string {
+	if trustProxy {
+		if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+			ip, _, _ := strings.Cut(forwarded, ",")
+			return strings.TrimSpace(ip)
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
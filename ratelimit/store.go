@@ -0,0 +1,162 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// Result of counting one request
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// Reset is the time until the limit is fully available again
+	Reset time.Duration
+	// RetryAfter is the time until the next request is allowed, zero when allowed
+	RetryAfter time.Duration
+}
+
+// Store keeps limiter state, a distributed implementation shares limits between
+// instances and must count atomically, for example with a Redis script
+type Store interface {
+	TokenBucket(ctx context.Context, key string, capacity int, rate float64, now time.Time) (Result, error)
+	SlidingWindow(ctx context.Context, key string, limit int, window time.Duration, now time.Time) (Result, error)
+}
+
+// MemoryStore keeps state in the process, idle entries are dropped once a minute
+type MemoryStore struct {
+	mu      *sync.Mutex
+	buckets map[string]*bucket
+	windows map[string]*window
+	swept   time.Time
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+	idle   time.Duration
+}
+
+type window struct {
+	start    time.Time
+	current  int
+	previous int
+	size     time.Duration
+}
+
+const sweepInterval = time.Minute
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		mu:      &sync.Mutex{},
+		buckets: make(map[string]*bucket),
+		windows: make(map[string]*window),
+	}
+}
+
+// TokenBucket takes a token from a bucket of capacity refilled with rate tokens per second
+func (s *MemoryStore) TokenBucket(_ context.Context, key string, capacity int, rate float64, now time.Time) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sweep(now)
+
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(capacity), last: now, idle: seconds(float64(capacity) / rate)}
+		s.buckets[key] = b
+	}
+	b.tokens = math.Min(float64(capacity), b.tokens+now.Sub(b.last).Seconds()*rate)
+	b.last = now
+
+	res := Result{Limit: capacity}
+	if b.tokens >= 1 {
+		b.tokens--
+		res.Allowed = true
+	} else {
+		res.RetryAfter = seconds((1 - b.tokens) / rate)
+	}
+	res.Remaining = int(b.tokens)
+	res.Reset = seconds((float64(capacity) - b.tokens) / rate)
+	return res, nil
+}
+
+// SlidingWindow counts requests in the last window weighting the previous fixed window by its overlap
+func (s *MemoryStore) SlidingWindow(_ context.Context, key string, limit int, size time.Duration, now time.Time) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sweep(now)
+
+	w, ok := s.windows[key]
+	if !ok {
+		w = &window{start: now.Truncate(size), size: size}
+		s.windows[key] = w
+	}
+	if elapsed := now.Sub(w.start); elapsed >= size {
+		w.previous = 0
+		if elapsed < 2*size {
+			w.previous = w.current
+		}
+		w.current = 0
+		w.start = now.Truncate(size)
+	}
+
+	res := slidingResult(limit, size, now.Sub(w.start), w.previous, w.current)
+	if res.Allowed {
+		w.current++
+	}
+	return res, nil
+}
+
+// slidingResult evaluates a request against the weighted count, it is shared by stores keeping the same counters
+func slidingResult(limit int, size, elapsed time.Duration, previous, current int) Result {
+	progress := float64(elapsed) / float64(size)
+	count := float64(previous)*(1-progress) + float64(current)
+	res := Result{Limit: limit, Reset: size - elapsed}
+
+	if count+1 <= float64(limit) {
+		res.Allowed = true
+		res.Remaining = int(float64(limit) - count - 1)
+		return res
+	}
+
+	// time until the weighted count drops below limit-1
+	free := float64(limit - 1)
+	switch {
+	case current <= limit-1 && previous > 0:
+		res.RetryAfter = time.Duration((1-(free-float64(current))/float64(previous))*float64(size)) - elapsed
+	default:
+		res.RetryAfter = size - elapsed
+		if current > 0 {
+			res.RetryAfter += time.Duration((1 - free/float64(current)) * float64(size))
+		}
+	}
+	if res.RetryAfter < 0 {
+		res.RetryAfter = 0
+	}
+	res.Reset = max(res.Reset, res.RetryAfter)
+	return res
+}
+
+// sweep must be called with the lock held
+func (s *MemoryStore) sweep(now time.Time) {
+	if now.Sub(s.swept) < sweepInterval {
+		return
+	}
+	s.swept = now
+	for key, b := range s.buckets {
+		if now.Sub(b.last) > b.idle {
+			delete(s.buckets, key)
+		}
+	}
+	for key, w := range s.windows {
+		if now.Sub(w.start) >= 2*w.size {
+			delete(s.windows, key)
+		}
+	}
+}
+
+func seconds(value float64) time.Duration {
+	return time.Duration(value * float64(time.Second))
+}
@@ -0,0 +1,110 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func approx(a, b time.Duration) bool {
+	d := a - b
+	return d > -time.Millisecond && d < time.Millisecond
+}
+
+func TestMemoryStoreTokenBucket(t *testing.T) {
+	store := NewMemoryStore()
+	start := time.Unix(1000, 0)
+
+	steps := []struct {
+		after      time.Duration
+		allowed    bool
+		remaining  int
+		reset      time.Duration
+		retryAfter time.Duration
+	}{
+		{after: 0, allowed: true, remaining: 1, reset: time.Second},
+		{after: 0, allowed: true, remaining: 0, reset: 2 * time.Second},
+		{after: 0, allowed: false, remaining: 0, reset: 2 * time.Second, retryAfter: time.Second},
+		{after: 500 * time.Millisecond, allowed: false, remaining: 0, reset: 1500 * time.Millisecond, retryAfter: 500 * time.Millisecond},
+		{after: time.Second, allowed: true, remaining: 0, reset: 2 * time.Second},
+		{after: 10 * time.Second, allowed: true, remaining: 1, reset: time.Second},
+	}
+
+	for i, step := range steps {
+		res, err := store.TokenBucket(context.Background(), "key", 2, 1, start.Add(step.after))
+		if err != nil {
+			t.Fatalf("step %d: %v", i, err)
+		}
+		if res.Allowed != step.allowed || res.Remaining != step.remaining || res.Limit != 2 {
+			t.Errorf("step %d: got allowed %v remaining %d limit %d, want %v %d 2", i, res.Allowed, res.Remaining, res.Limit, step.allowed, step.remaining)
+		}
+		if !approx(res.Reset, step.reset) || !approx(res.RetryAfter, step.retryAfter) {
+			t.Errorf("step %d: got reset %s retry after %s, want %s %s", i, res.Reset, res.RetryAfter, step.reset, step.retryAfter)
+		}
+	}
+}
+
+func TestMemoryStoreSlidingWindow(t *testing.T) {
+	store := NewMemoryStore()
+	start := time.Unix(1000, 0)
+	size := 10 * time.Second
+
+	steps := []struct {
+		after   time.Duration
+		allowed bool
+	}{
+		{after: 0, allowed: true},
+		{after: time.Second, allowed: true},
+		{after: 2 * time.Second, allowed: false},
+		// the previous window still counts 2 at its end
+		{after: 10 * time.Second, allowed: false},
+		{after: 15 * time.Second, allowed: true},
+		{after: 15 * time.Second, allowed: false},
+		// windows older than the previous one are forgotten
+		{after: 40 * time.Second, allowed: true},
+		{after: 40 * time.Second, allowed: true},
+	}
+
+	for i, step := range steps {
+		res, err := store.SlidingWindow(context.Background(), "key", 2, size, start.Add(step.after))
+		if err != nil {
+			t.Fatalf("step %d: %v", i, err)
+		}
+		if res.Allowed != step.allowed {
+			t.Errorf("step %d: got allowed %v, want %v", i, res.Allowed, step.allowed)
+		}
+	}
+}
+
+func TestSlidingResult(t *testing.T) {
+	size := 10 * time.Second
+	tests := []struct {
+		name       string
+		limit      int
+		elapsed    time.Duration
+		previous   int
+		current    int
+		allowed    bool
+		remaining  int
+		reset      time.Duration
+		retryAfter time.Duration
+	}{
+		{name: "empty", limit: 10, allowed: true, remaining: 9, reset: size},
+		{name: "weighted previous", limit: 10, elapsed: 5 * time.Second, previous: 10, current: 4, allowed: true, remaining: 0, reset: 5 * time.Second},
+		{name: "previous slides out", limit: 10, elapsed: 5 * time.Second, previous: 10, current: 5, reset: 5 * time.Second, retryAfter: time.Second},
+		{name: "current full", limit: 10, elapsed: 2 * time.Second, current: 10, reset: 9 * time.Second, retryAfter: 9 * time.Second},
+		{name: "single request", limit: 1, current: 1, reset: 20 * time.Second, retryAfter: 20 * time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := slidingResult(tt.limit, size, tt.elapsed, tt.previous, tt.current)
+			if res.Allowed != tt.allowed || res.Remaining != tt.remaining || res.Limit != tt.limit {
+				t.Errorf("got allowed %v remaining %d limit %d, want %v %d %d", res.Allowed, res.Remaining, res.Limit, tt.allowed, tt.remaining, tt.limit)
+			}
+			if !approx(res.Reset, tt.reset) || !approx(res.RetryAfter, tt.retryAfter) {
+				t.Errorf("got reset %s retry after %s, want %s %s", res.Reset, res.RetryAfter, tt.reset, tt.retryAfter)
+			}
+		})
+	}
+}
@@ -0,0 +1,501 @@
+package rest
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	durationType        = reflect.TypeOf(time.Duration(0))
+	timeType            = reflect.TypeOf(time.Time{})
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+type structPlan struct {
+	fields []*fieldPlan
+	// body is set when some field is decoded from the JSON body
+	body bool
+}
+
+type fieldPlan struct {
+	index  []int
+	typ    reflect.Type
+	name   string
+	source string
+	key    string
+	def    string
+	rules  []rule
+	nested *structPlan
+	// each is set when nested applies to slice elements
+	each bool
+}
+
+type rule struct {
+	name  string
+	arg   string
+	num   float64
+	oneof []string
+}
+
+// planOf reads binding and validation tags of a request struct once per adapter
+func planOf(t reflect.Type) (*structPlan, error) {
+	if t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("request type %s is not a struct", t)
+	}
+	return planStruct(t, map[reflect.Type]*structPlan{})
+}
+
+func planStruct(t reflect.Type, seen map[reflect.Type]*structPlan) (*structPlan, error) {
+	if plan, ok := seen[t]; ok {
+		return plan, nil
+	}
+	plan := &structPlan{fields: make([]*fieldPlan, 0, t.NumField())}
+	seen[t] = plan
+
+	err := plan.add(t, nil, seen)
+	if err != nil {
+		return nil, err
+	}
+	return plan, nil
+}
+
+// add plans the fields of t found at index, fields of embedded structs are promoted as encoding/json does
+func (p *structPlan) add(t reflect.Type, index []int, seen map[reflect.Type]*structPlan) error {
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		fieldIndex := append(append(make([]int, 0, len(index)+1), index...), i)
+
+		if embedded := embeddedStruct(sf); embedded != nil {
+			// like encoding/json, pointers to unexported structs can not be allocated
+			if !sf.IsExported() && sf.Type.Kind() == reflect.Pointer {
+				continue
+			}
+			err := p.add(embedded, fieldIndex, seen)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		if !sf.IsExported() {
+			continue
+		}
+
+		field := &fieldPlan{index: fieldIndex, typ: sf.Type, def: sf.Tag.Get("default")}
+		switch {
+		case sf.Tag.Get("path") != "":
+			field.source, field.key = SourcePath, sf.Tag.Get("path")
+		case sf.Tag.Get("query") != "":
+			field.source, field.key = SourceQuery, sf.Tag.Get("query")
+		case sf.Tag.Get("header") != "":
+			field.source, field.key = SourceHeader, sf.Tag.Get("header")
+		default:
+			field.source, field.key = SourceBody, jsonName(sf)
+			if field.key == "-" {
+				continue
+			}
+			p.body = true
+		}
+		field.name = field.key
+
+		if field.source != SourceBody && !bindable(sf.Type) {
+			return fmt.Errorf("field %s: type %s can not be bound from %s", sf.Name, sf.Type, field.source)
+		}
+
+		rules, err := parseRules(sf.Tag.Get("validate"))
+		if err != nil {
+			return fmt.Errorf("field %s: %w", sf.Name, err)
+		}
+		field.rules = rules
+
+		if field.source == SourceBody {
+			elem, each := nestedStruct(sf.Type)
+			if elem != nil {
+				field.nested, err = planStruct(elem, seen)
+				if err != nil {
+					return err
+				}
+				field.each = each
+			}
+		}
+
+		p.fields = append(p.fields, field)
+	}
+	return nil
+}
+
+// embeddedStruct returns the type of an embedded struct whose fields are promoted, nil for other fields
+func embeddedStruct(sf reflect.StructField) reflect.Type {
+	if !sf.Anonymous || sf.Tag.Get("json") != "" || sf.Tag.Get("path") != "" || sf.Tag.Get("query") != "" || sf.Tag.Get("header") != "" {
+		return nil
+	}
+	t := sf.Type
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+	return t
+}
+
+// fieldOf returns the field at index, nil embedded pointers are allocated when alloc is set and reported missing otherwise
+func fieldOf(value reflect.Value, index []int, alloc bool) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && value.Kind() == reflect.Pointer {
+			if value.IsNil() {
+				if !alloc {
+					return reflect.Value{}, false
+				}
+				value.Set(reflect.New(value.Type().Elem()))
+			}
+			value = value.Elem()
+		}
+		value = value.Field(x)
+	}
+	return value, true
+}
+
+// clearUnbound zeroes fields filled by the body decoder that are bound from the path, query or headers,
+// a client must not set them by sending the field in the body
+func (p *structPlan) clearUnbound(value reflect.Value) {
+	for _, field := range p.fields {
+		if field.source == SourceBody {
+			continue
+		}
+		if fieldValue, ok := fieldOf(value, field.index, false); ok {
+			fieldValue.Set(reflect.Zero(field.typ))
+		}
+	}
+}
+
+func jsonName(sf reflect.StructField) string {
+	name, _, _ := strings.Cut(sf.Tag.Get("json"), ",")
+	if name == "" {
+		return sf.Name
+	}
+	return name
+}
+
+func nestedStruct(t reflect.Type) (reflect.Type, bool) {
+	each := false
+	if t.Kind() == reflect.Slice {
+		t, each = t.Elem(), true
+	}
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct || t == timeType {
+		return nil, false
+	}
+	return t, each
+}
+
+func bindable(t reflect.Type) bool {
+	if t.Kind() == reflect.Slice {
+		t = t.Elem()
+	}
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == timeType || reflect.PointerTo(t).Implements(textUnmarshalerType) {
+		return true
+	}
+	switch t.Kind() {
+	case reflect.String, reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+func parseRules(tag string) ([]rule, error) {
+	if tag == "" {
+		return nil, nil
+	}
+	rules := make([]rule, 0, 2)
+	for _, part := range strings.Split(tag, ",") {
+		name, arg, _ := strings.Cut(strings.TrimSpace(part), "=")
+		r := rule{name: name, arg: arg}
+		switch name {
+		case "required":
+		case "min", "max":
+			num, err := strconv.ParseFloat(arg, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s rule %q", name, arg)
+			}
+			r.num = num
+		case "oneof":
+			r.oneof = strings.Fields(arg)
+			if len(r.oneof) == 0 {
+				return nil, errors.New("empty oneof rule")
+			}
+		default:
+			return nil, fmt.Errorf("unknown validate rule %q", name)
+		}
+		rules = append(rules, r)
+	}
+	return rules, nil
+}
+
+// validate appends rule violations to errs, bound values are checked even when zero
+// and fields whose binding failed are skipped. Body fields are present when body holds
+// their key, it is nil for bodies of other codecs than JSON where zero values count as absent
+func (p *structPlan) validate(value reflect.Value, prefix string, bound map[*fieldPlan]bool, body *presence, errs []FieldError) []FieldError {
+	for _, field := range p.fields {
+		ok, present := bound[field]
+		if present && !ok {
+			continue
+		}
+		var fieldBody *presence
+		if field.source == SourceBody {
+			fieldBody, present = body.key(field.key)
+		}
+
+		fieldValue, ok := fieldOf(value, field.index, false)
+		if !ok {
+			fieldValue = reflect.Zero(field.typ)
+		}
+		name := prefix + field.name
+		for _, r := range field.rules {
+			// required body fields must not be zero even when sent
+			if message := r.check(fieldValue, present && (r.name != "required" || field.source != SourceBody)); message != "" {
+				errs = append(errs, FieldError{Field: name, In: field.source, Message: message})
+				break
+			}
+		}
+
+		if field.nested == nil {
+			continue
+		}
+		if !field.each {
+			if nested, ok := indirect(fieldValue); ok {
+				errs = field.nested.validate(nested, name+".", nil, fieldBody, errs)
+			}
+			continue
+		}
+		for i := 0; i < fieldValue.Len(); i++ {
+			if nested, ok := indirect(fieldValue.Index(i)); ok {
+				errs = field.nested.validate(nested, fmt.Sprintf("%s[%d].", name, i), nil, fieldBody.item(i), errs)
+			}
+		}
+	}
+	return errs
+}
+
+// presence holds the keys sent in a JSON body so that zero values sent by clients are validated
+type presence struct {
+	keys  map[string]*presence
+	items []*presence
+}
+
+func parsePresence(data []byte) *presence {
+	p := &presence{}
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 {
+		return p
+	}
+
+	switch data[0] {
+	case '{':
+		var object map[string]json.RawMessage
+		if json.Unmarshal(data, &object) != nil {
+			return p
+		}
+		p.keys = make(map[string]*presence, len(object))
+		for key, raw := range object {
+			// null leaves the field untouched like a missing key
+			if bytes.Equal(bytes.TrimSpace(raw), []byte("null")) {
+				continue
+			}
+			p.keys[strings.ToLower(key)] = parsePresence(raw)
+		}
+	case '[':
+		var array []json.RawMessage
+		if json.Unmarshal(data, &array) != nil {
+			return p
+		}
+		p.items = make([]*presence, len(array))
+		for i, raw := range array {
+			p.items[i] = parsePresence(raw)
+		}
+	}
+	return p
+}
+
+// key matches case-insensitively like encoding/json
+func (p *presence) key(name string) (*presence, bool) {
+	if p == nil {
+		return nil, false
+	}
+	child, ok := p.keys[strings.ToLower(name)]
+	return child, ok
+}
+
+func (p *presence) item(i int) *presence {
+	if p == nil || i >= len(p.items) {
+		return nil
+	}
+	return p.items[i]
+}
+
+func indirect(value reflect.Value) (reflect.Value, bool) {
+	if value.Kind() == reflect.Pointer {
+		if value.IsNil() {
+			return value, false
+		}
+		value = value.Elem()
+	}
+	return value, true
+}
+
+// check returns a message when value breaks the rule,
+// values not present in the request only fail required
+func (r rule) check(value reflect.Value, present bool) string {
+	if r.name == "required" {
+		if !present && value.IsZero() {
+			return "is required"
+		}
+		return ""
+	}
+
+	value, ok := indirect(value)
+	if !ok || (!present && value.IsZero()) {
+		return ""
+	}
+
+	switch r.name {
+	case "min", "max":
+		size, unit := measure(value)
+		if r.name == "min" && size < r.num {
+			return fmt.Sprintf("must be at least %s%s", r.arg, unit)
+		}
+		if r.name == "max" && size > r.num {
+			return fmt.Sprintf("must be at most %s%s", r.arg, unit)
+		}
+	case "oneof":
+		text := fmt.Sprint(value.Interface())
+		for _, option := range r.oneof {
+			if text == option {
+				return ""
+			}
+		}
+		return "must be one of " + strings.Join(r.oneof, ", ")
+	}
+	return ""
+}
+
+// measure returns the number compared by min and max, the length for strings and collections
+func measure(value reflect.Value) (float64, string) {
+	switch value.Kind() {
+	case reflect.String:
+		return float64(len([]rune(value.String()))), " characters"
+	case reflect.Slice, reflect.Map, reflect.Array:
+		return float64(value.Len()), " items"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(value.Int()), ""
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(value.Uint()), ""
+	case reflect.Float32, reflect.Float64:
+		return value.Float(), ""
+	}
+	return 0, ""
+}
+
+func setValues(value reflect.Value, values []string) error {
+	if value.Kind() == reflect.Slice && value.Type().Elem().Kind() != reflect.Uint8 {
+		slice := reflect.MakeSlice(value.Type(), len(values), len(values))
+		for i, text := range values {
+			err := setValue(slice.Index(i), text)
+			if err != nil {
+				return err
+			}
+		}
+		value.Set(slice)
+		return nil
+	}
+	return setValue(value, values[0])
+}
+
+func setValue(value reflect.Value, text string) error {
+	if value.Kind() == reflect.Pointer {
+		ptr := reflect.New(value.Type().Elem())
+		err := setValue(ptr.Elem(), text)
+		if err != nil {
+			return err
+		}
+		value.Set(ptr)
+		return nil
+	}
+
+	if unmarshaler, ok := value.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		return unmarshaler.UnmarshalText([]byte(text))
+	}
+
+	if value.Type() == durationType {
+		duration, err := time.ParseDuration(text)
+		if err != nil {
+			return errors.New("must be a duration")
+		}
+		value.SetInt(int64(duration))
+		return nil
+	}
+
+	switch value.Kind() {
+	case reflect.String:
+		value.SetString(text)
+	case reflect.Bool:
+		parsed, err := strconv.ParseBool(text)
+		if err != nil {
+			return errors.New("must be a boolean")
+		}
+		value.SetBool(parsed)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		parsed, err := strconv.ParseInt(text, 10, value.Type().Bits())
+		if err != nil {
+			return errors.New("must be an integer")
+		}
+		value.SetInt(parsed)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		parsed, err := strconv.ParseUint(text, 10, value.Type().Bits())
+		if err != nil {
+			return errors.New("must be a non-negative integer")
+		}
+		value.SetUint(parsed)
+	case reflect.Float32, reflect.Float64:
+		parsed, err := strconv.ParseFloat(text, value.Type().Bits())
+		if err != nil {
+			return errors.New("must be a number")
+		}
+		value.SetFloat(parsed)
+	default:
+		return fmt.Errorf("unsupported type %s", value.Type())
+	}
+	return nil
+}
+
+func typeName(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.String:
+		return "a string"
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "an integer"
+	case reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.Slice, reflect.Array:
+		return "an array"
+	default:
+		return "an object"
+	}
+}
@@ -0,0 +1,203 @@
+package rest
+
+import (
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type bindItem struct {
+	SKU   string `json:"sku" validate:"required"`
+	Count int    `json:"count" validate:"min=1"`
+}
+
+type bindRequest struct {
+	ID      int           `path:"id" validate:"min=1"`
+	Limit   int           `query:"limit" default:"20" validate:"min=1,max=100"`
+	Offset  int           `query:"offset"`
+	Tags    []string      `query:"tag"`
+	Timeout time.Duration `query:"timeout"`
+	Trace   *string       `header:"X-Trace-Id"`
+	Name    string        `json:"name" validate:"required,max=5"`
+	Kind    string        `json:"kind" validate:"oneof=a b"`
+	Size    int           `json:"size" validate:"min=1"`
+	Items   []bindItem    `json:"items"`
+	Parent  *bindItem     `json:"parent"`
+	Hidden  string        `json:"-"`
+}
+
+func TestBind(t *testing.T) {
+	vars := map[string]string{"id": "7"}
+	tests := []struct {
+		name   string
+		target string
+		vars   map[string]string
+		header http.Header
+		body   string
+		want   func(req bindRequest) bool
+		errors []FieldError
+	}{
+		{
+			name:   "values and defaults",
+			target: "/items?tag=a&tag=b&timeout=2s",
+			vars:   vars,
+			header: http.Header{"X-Trace-Id": {"abc"}},
+			body:   `{"name":"box","kind":"a","items":[{"sku":"x","count":2}]}`,
+			want: func(req bindRequest) bool {
+				return req.ID == 7 && req.Limit == 20 && len(req.Tags) == 2 && req.Timeout == 2*time.Second &&
+					req.Trace != nil && *req.Trace == "abc" && req.Name == "box" && len(req.Items) == 1
+			},
+		},
+		{
+			name:   "body can not set bound or ignored fields",
+			target: "/items",
+			vars:   vars,
+			body:   `{"name":"box","ID":9,"Limit":500,"Hidden":"x"}`,
+			want: func(req bindRequest) bool {
+				return req.ID == 7 && req.Limit == 20 && req.Hidden == ""
+			},
+		},
+		{
+			name:   "missing required body field",
+			target: "/items",
+			vars:   vars,
+			body:   `{"kind":"a"}`,
+			errors: []FieldError{{Field: "name", In: SourceBody, Message: "is required"}},
+		},
+		{
+			name:   "required body field sent empty",
+			target: "/items",
+			vars:   vars,
+			body:   `{"name":""}`,
+			errors: []FieldError{{Field: "name", In: SourceBody, Message: "is required"}},
+		},
+		{
+			name:   "null is absent",
+			target: "/items",
+			vars:   vars,
+			body:   `{"name":"box","size":null}`,
+			want: func(req bindRequest) bool {
+				return req.Size == 0
+			},
+		},
+		{
+			name:   "zero values sent are validated",
+			target: "/items",
+			vars:   vars,
+			body:   `{"name":"box","size":0,"kind":""}`,
+			errors: []FieldError{
+				{Field: "kind", In: SourceBody, Message: "must be one of a, b"},
+				{Field: "size", In: SourceBody, Message: "must be at least 1"},
+			},
+		},
+		{
+			name:   "zero query value sent is validated",
+			target: "/items?limit=0",
+			vars:   vars,
+			body:   `{"name":"box"}`,
+			errors: []FieldError{{Field: "limit", In: SourceQuery, Message: "must be at least 1"}},
+		},
+		{
+			name:   "bounds",
+			target: "/items?limit=101",
+			vars:   map[string]string{"id": "0"},
+			body:   `{"name":"toolong"}`,
+			errors: []FieldError{
+				{Field: "id", In: SourcePath, Message: "must be at least 1"},
+				{Field: "limit", In: SourceQuery, Message: "must be at most 100"},
+				{Field: "name", In: SourceBody, Message: "must be at most 5 characters"},
+			},
+		},
+		{
+			name:   "nested structs",
+			target: "/items",
+			vars:   vars,
+			body:   `{"name":"box","items":[{"sku":"x","count":1},{"count":0}],"parent":{"sku":"p"}}`,
+			errors: []FieldError{
+				{Field: "items[1].sku", In: SourceBody, Message: "is required"},
+				{Field: "items[1].count", In: SourceBody, Message: "must be at least 1"},
+			},
+		},
+		{
+			name:   "unparsable values",
+			target: "/items?offset=x&timeout=soon",
+			vars:   map[string]string{"id": "one"},
+			body:   `{"name":"box"}`,
+			errors: []FieldError{
+				{Field: "id", In: SourcePath, Message: "must be an integer"},
+				{Field: "offset", In: SourceQuery, Message: "must be an integer"},
+				{Field: "timeout", In: SourceQuery, Message: "must be a duration"},
+			},
+		},
+		{
+			name:   "wrong body type",
+			target: "/items",
+			vars:   vars,
+			body:   `{"name":1}`,
+			errors: []FieldError{{Field: "name", In: SourceBody, Message: "must be a string"}},
+		},
+		{
+			name:   "invalid JSON",
+			target: "/items",
+			vars:   vars,
+			body:   `{"name":`,
+			errors: []FieldError{{In: SourceBody, Message: "invalid JSON"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := typedRequest(http.MethodPost, tt.target, tt.body, tt.vars, tt.header)
+			req, err := callTyped[bindRequest](t, r)
+
+			if tt.errors == nil {
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !tt.want(req) {
+					t.Errorf("got %+v", req)
+				}
+				return
+			}
+			var validationErr *ValidationError
+			if !errors.As(err, &validationErr) {
+				t.Fatalf("got error %v, want a validation error", err)
+			}
+			if !reflect.DeepEqual(validationErr.Fields, tt.errors) {
+				t.Errorf("got fields %+v, want %+v", validationErr.Fields, tt.errors)
+			}
+		})
+	}
+}
+
+func TestPlanErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		typ  any
+	}{
+		{name: "not a struct", typ: 1},
+		{name: "unbindable query", typ: struct {
+			Filter map[string]string `query:"filter"`
+		}{}},
+		{name: "unknown rule", typ: struct {
+			Name string `json:"name" validate:"email"`
+		}{}},
+		{name: "invalid bound", typ: struct {
+			Limit int `query:"limit" validate:"min=x"`
+		}{}},
+		{name: "empty oneof", typ: struct {
+			Kind string `json:"kind" validate:"oneof="`
+		}{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := planOf(reflect.TypeOf(tt.typ))
+			if err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,272 @@
+package rest
+
+import (
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+var (
+	UnsupportedValue     = errors.New("value not supported by codec")
+	NotAcceptable        = errors.New("none of the accepted media types is supported")
+	UnsupportedMediaType = errors.New("unsupported media type")
+)
+
+var unsupportedMediaType = &Error{Status: http.StatusUnsupportedMediaType, Code: "unsupported_media_type", Err: UnsupportedMediaType}
+
+// Codec encodes responses and decodes request bodies of one media type
+type Codec interface {
+	ContentType() string
+	// Marshal and Unmarshal return UnsupportedValue for values the format can not represent
+	Marshal(v any) ([]byte, error)
+	Unmarshal(data []byte, v any) error
+}
+
+// Codecs is a registry of codecs negotiated on Accept and Content-Type,
+// the first registered codec is used when the request does not ask for any, JSON while it is empty
+type Codecs struct {
+	mu     *sync.RWMutex
+	list   []Codec
+	byType map[string]Codec
+}
+
+func NewCodecs() *Codecs {
+	return &Codecs{
+		mu:     &sync.RWMutex{},
+		list:   make([]Codec, 0, 4),
+		byType: make(map[string]Codec, 8),
+	}
+}
+
+// DefaultCodecs returns JSON, MessagePack, protobuf and XML codecs with JSON as default
+func DefaultCodecs() *Codecs {
+	codecs := NewCodecs()
+	codecs.Register(JSONCodec{})
+	codecs.Register(MsgpackCodec{}, "application/x-msgpack", "application/vnd.msgpack")
+	codecs.Register(ProtobufCodec{}, "application/protobuf", "application/vnd.google.protobuf")
+	codecs.Register(XMLCodec{}, "text/xml")
+	return codecs
+}
+
+// Register adds codec for its content type and aliases replacing a codec registered for them before
+func (c *Codecs) Register(codec Codec, aliases ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	contentType := strings.ToLower(codec.ContentType())
+	if previous, ok := c.byType[contentType]; ok {
+		for i, registered := range c.list {
+			if registered == previous {
+				c.list = append(c.list[:i], c.list[i+1:]...)
+				break
+			}
+		}
+	}
+
+	c.list = append(c.list, codec)
+	c.byType[contentType] = codec
+	for _, alias := range aliases {
+		c.byType[strings.ToLower(alias)] = codec
+	}
+}
+
+// ForContentType returns the codec decoding a request body, JSON is assumed without Content-Type
+func (c *Codecs) ForContentType(contentType string) (Codec, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if contentType == "" {
+		return c.first(), nil
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, UnsupportedMediaType
+	}
+	codec, ok := c.byType[mediaType]
+	if !ok {
+		return nil, UnsupportedMediaType
+	}
+	return codec, nil
+}
+
+// Negotiate returns codecs acceptable for the Accept header ordered by preference,
+// the default codec goes first when the top ranked media type is not supported
+// so that browsers sending text/html and wildcards get JSON
+func (c *Codecs) Negotiate(accept string) ([]Codec, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if strings.TrimSpace(accept) == "" || len(c.list) == 0 {
+		return []Codec{c.first()}, nil
+	}
+
+	ranges := parseAccept(accept)
+	res := make([]Codec, 0, len(c.list))
+	seen := make(map[Codec]bool, len(c.list))
+	excluded := make(map[Codec]bool)
+	for _, r := range ranges {
+		if r.q == 0 {
+			if codec, ok := c.byType[r.mediaType]; ok {
+				excluded[codec] = true
+			}
+		}
+	}
+
+	top := true
+	preferDefault := false
+	for _, r := range ranges {
+		if r.q == 0 {
+			continue
+		}
+		matched := c.match(r.mediaType)
+		if top {
+			preferDefault = len(matched) == 0
+			top = false
+		}
+		for _, codec := range matched {
+			if !seen[codec] && !excluded[codec] {
+				seen[codec] = true
+				res = append(res, codec)
+			}
+		}
+	}
+
+	if len(res) == 0 {
+		return nil, NotAcceptable
+	}
+	if def := c.first(); preferDefault && seen[def] && res[0] != def {
+		res = append([]Codec{def}, slices.DeleteFunc(res, func(codec Codec) bool { return codec == def })...)
+	}
+	return res, nil
+}
+
+// first must be called with the lock held
+func (c *Codecs) first() Codec {
+	if len(c.list) == 0 {
+		return JSONCodec{}
+	}
+	return c.list[0]
+}
+
+func (c *Codecs) match(mediaType string) []Codec {
+	switch {
+	case mediaType == "*/*":
+		return c.list
+	case strings.HasSuffix(mediaType, "/*"):
+		prefix := strings.TrimSuffix(mediaType, "*")
+		res := make([]Codec, 0, len(c.list))
+		for _, codec := range c.list {
+			if strings.HasPrefix(codec.ContentType(), prefix) {
+				res = append(res, codec)
+			}
+		}
+		return res
+	default:
+		if codec, ok := c.byType[mediaType]; ok {
+			return []Codec{codec}
+		}
+		return nil
+	}
+}
+
+type acceptRange struct {
+	mediaType string
+	q         float64
+}
+
+// parseAccept orders media ranges by quality, more specific ranges first on equal quality
+func parseAccept(accept string) []acceptRange {
+	ranges := make([]acceptRange, 0, 4)
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if value, ok := params["q"]; ok {
+			if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+				q = parsed
+			}
+		}
+		ranges = append(ranges, acceptRange{mediaType: mediaType, q: q})
+	}
+
+	sort.SliceStable(ranges, func(i, j int) bool {
+		if ranges[i].q != ranges[j].q {
+			return ranges[i].q > ranges[j].q
+		}
+		return strings.Count(ranges[i].mediaType, "*") < strings.Count(ranges[j].mediaType, "*")
+	})
+	return ranges
+}
+
+type codecKey struct{}
+
+type negotiated struct {
+	codecs   *Codecs
+	response []Codec
+}
+
+// Decode reads the request body into v with the codec matching its Content-Type
+func Decode(r *http.Request, v any) error {
+	_, _, err := decode(r, v)
+	return err
+}
+
+// decode is Decode returning the body read and the codec used
+func decode(r *http.Request, v any) ([]byte, Codec, error) {
+	data, err := io.ReadAll(r.Body)
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		return nil, nil, RequestTooLarge.Wrap(err)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(data) == 0 {
+		return nil, nil, io.EOF
+	}
+
+	codec := Codec(JSONCodec{})
+	if n, ok := r.Context().Value(codecKey{}).(*negotiated); ok {
+		codec, err = n.codecs.ForContentType(r.Header.Get("Content-Type"))
+		if err != nil {
+			return nil, nil, unsupportedMediaType
+		}
+	}
+	err = codec.Unmarshal(data, v)
+	if errors.Is(err, UnsupportedValue) {
+		// the body is valid but the handler does not take this media type
+		return nil, nil, unsupportedMediaType
+	}
+	return data, codec, err
+}
+
+// encode marshals v with the first accepted codec supporting it,
+// errors fall back to JSON so that they are never lost to negotiation
+func encode(r *http.Request, v any, isError bool) (Codec, []byte, error) {
+	codecs := []Codec{JSONCodec{}}
+	if n, ok := r.Context().Value(codecKey{}).(*negotiated); ok {
+		codecs = n.response
+	}
+
+	for _, codec := range codecs {
+		data, err := codec.Marshal(v)
+		if errors.Is(err, UnsupportedValue) {
+			continue
+		}
+		return codec, data, err
+	}
+
+	if isError {
+		data, err := JSONCodec{}.Marshal(v)
+		return JSONCodec{}, data, err
+	}
+	return nil, nil, NotAcceptable
+}
@@ -0,0 +1,163 @@
+package rest
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/DoomLordor/logger"
+)
+
+func contentTypes(codecs []Codec) []string {
+	res := make([]string, len(codecs))
+	for i, codec := range codecs {
+		res[i] = codec.ContentType()
+	}
+	return res
+}
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		name   string
+		accept string
+		want   string
+		err    error
+	}{
+		{name: "empty", accept: "", want: "application/json"},
+		{name: "wildcard", accept: "*/*", want: "application/json"},
+		{name: "explicit", accept: "application/xml", want: "application/xml"},
+		{name: "alias", accept: "application/x-msgpack", want: "application/msgpack"},
+		{name: "q values", accept: "application/json;q=0.5, application/xml", want: "application/xml"},
+		{name: "specific before wildcard", accept: "*/*, application/xml", want: "application/xml"},
+		{name: "type wildcard", accept: "application/*", want: "application/json"},
+		{name: "type wildcard after unsupported", accept: "text/*, application/*;q=0.5", want: "application/json"},
+		{name: "excluded", accept: "application/json;q=0, */*", want: "application/msgpack"},
+		{name: "browser", accept: "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8", want: "application/json"},
+		{name: "top ranked unsupported", accept: "text/html, application/xml;q=0.5, application/json;q=0.1", want: "application/json"},
+		{name: "unsupported without json", accept: "text/html, application/xml;q=0.5", want: "application/xml"},
+		{name: "not acceptable", accept: "text/html", err: NotAcceptable},
+		{name: "all excluded", accept: "application/json;q=0", err: NotAcceptable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			codecs, err := DefaultCodecs().Negotiate(tt.accept)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("got error %v, want %v", err, tt.err)
+			}
+			if tt.err != nil {
+				return
+			}
+			if got := contentTypes(codecs)[0]; got != tt.want {
+				t.Errorf("got %v, want %s first", contentTypes(codecs), tt.want)
+			}
+		})
+	}
+}
+
+func TestNegotiateEmptyRegistry(t *testing.T) {
+	codecs := NewCodecs()
+	for _, accept := range []string{"", "application/xml"} {
+		res, err := codecs.Negotiate(accept)
+		if err != nil || len(res) != 1 || res[0].ContentType() != jsonContentType {
+			t.Errorf("Accept %q: got %v %v, want JSON", accept, res, err)
+		}
+	}
+	codec, err := codecs.ForContentType("")
+	if err != nil || codec.ContentType() != jsonContentType {
+		t.Errorf("got %v %v, want JSON without Content-Type", codec, err)
+	}
+}
+
+func TestForContentType(t *testing.T) {
+	tests := []struct {
+		contentType string
+		want        string
+		err         error
+	}{
+		{contentType: "", want: "application/json"},
+		{contentType: "application/json; charset=utf-8", want: "application/json"},
+		{contentType: "Application/XML", want: "application/xml"},
+		{contentType: "text/xml", want: "application/xml"},
+		{contentType: "application/vnd.google.protobuf", want: "application/x-protobuf"},
+		{contentType: "text/plain", err: UnsupportedMediaType},
+		{contentType: "not a type;", err: UnsupportedMediaType},
+	}
+
+	for _, tt := range tests {
+		codec, err := DefaultCodecs().ForContentType(tt.contentType)
+		if !errors.Is(err, tt.err) {
+			t.Errorf("%q: got error %v, want %v", tt.contentType, err, tt.err)
+			continue
+		}
+		if tt.err == nil && codec.ContentType() != tt.want {
+			t.Errorf("%q: got %s, want %s", tt.contentType, codec.ContentType(), tt.want)
+		}
+	}
+}
+
+type codecRequest struct {
+	Name string `json:"name" xml:"name"`
+}
+
+type codecResponse struct {
+	Name string `json:"name" xml:"name"`
+}
+
+func serveCodec(hf HandlerFuncRest, contentType, accept, body string) *httptest.ResponseRecorder {
+	m := NewMiddlewares(nil, logger.NewLogger("test"), nil)
+	handler := m.CommonMiddleware(m.CodecMiddleware(m.HandleWrapper(hf)))
+
+	r := httptest.NewRequest(http.MethodPost, "/items", strings.NewReader(body))
+	if contentType != "" {
+		r.Header.Set("Content-Type", contentType)
+	}
+	if accept != "" {
+		r.Header.Set("Accept", accept)
+	}
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	return w
+}
+
+func TestCodecMiddleware(t *testing.T) {
+	typed := Typed(func(_ context.Context, req codecRequest) (codecResponse, int, error) {
+		return codecResponse{Name: req.Name}, http.StatusOK, nil
+	})
+	raw := func(r *http.Request) (any, int, error) {
+		return "uploaded", http.StatusCreated, nil
+	}
+
+	tests := []struct {
+		name        string
+		handler     HandlerFuncRest
+		contentType string
+		accept      string
+		body        string
+		code        int
+		response    string
+	}{
+		{name: "typed json", handler: typed, contentType: "application/json", body: `{"name":"box"}`, code: http.StatusOK, response: "application/json"},
+		{name: "typed xml", handler: typed, contentType: "application/xml", accept: "application/xml", body: `<codecRequest><name>box</name></codecRequest>`, code: http.StatusOK, response: "application/xml"},
+		{name: "typed browser", handler: typed, accept: "text/html,application/xml;q=0.9,*/*;q=0.8", body: `{"name":"box"}`, code: http.StatusOK, response: "application/json"},
+		{name: "typed unsupported body", handler: typed, contentType: "text/plain", body: "box", code: http.StatusUnsupportedMediaType},
+		{name: "typed not acceptable", handler: typed, accept: "text/html", body: `{"name":"box"}`, code: http.StatusNotAcceptable},
+		{name: "raw form", handler: raw, contentType: "application/x-www-form-urlencoded", body: "name=box", code: http.StatusCreated, response: "application/json"},
+		{name: "raw multipart", handler: raw, contentType: "multipart/form-data; boundary=x", accept: "text/html", body: "--x--", code: http.StatusCreated, response: "application/json"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serveCodec(tt.handler, tt.contentType, tt.accept, tt.body)
+			if w.Code != tt.code {
+				t.Fatalf("got status %d, want %d: %s", w.Code, tt.code, w.Body.String())
+			}
+			if tt.response != "" && w.Header().Get("Content-Type") != tt.response {
+				t.Errorf("got Content-Type %q, want %q", w.Header().Get("Content-Type"), tt.response)
+			}
+		})
+	}
+}
@@ -0,0 +1,100 @@
+package rest
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+
+	"github.com/vmihailenco/msgpack/v5"
+	"google.golang.org/protobuf/proto"
+)
+
+// JSONCodec matches the output of json.Encoder the server used before codecs
+type JSONCodec struct{}
+
+func (JSONCodec) ContentType() string {
+	return "application/json"
+}
+
+func (JSONCodec) Marshal(v any) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+func (JSONCodec) Unmarshal(data []byte, v any) error {
+	return json.Unmarshal(data, v)
+}
+
+// MsgpackCodec uses json tags so that both formats share field names
+type MsgpackCodec struct{}
+
+func (MsgpackCodec) ContentType() string {
+	return "application/msgpack"
+}
+
+func (MsgpackCodec) Marshal(v any) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	encoder := msgpack.NewEncoder(buf)
+	encoder.SetCustomStructTag("json")
+	err := encoder.Encode(v)
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (MsgpackCodec) Unmarshal(data []byte, v any) error {
+	decoder := msgpack.NewDecoder(bytes.NewReader(data))
+	decoder.SetCustomStructTag("json")
+	return decoder.Decode(v)
+}
+
+// ProtobufCodec only handles proto.Message values
+type ProtobufCodec struct{}
+
+func (ProtobufCodec) ContentType() string {
+	return "application/x-protobuf"
+}
+
+func (ProtobufCodec) Marshal(v any) ([]byte, error) {
+	msg, ok := v.(proto.Message)
+	if !ok {
+		return nil, fmt.Errorf("%w: %T is not a proto message", UnsupportedValue, v)
+	}
+	return proto.Marshal(msg)
+}
+
+func (ProtobufCodec) Unmarshal(data []byte, v any) error {
+	msg, ok := v.(proto.Message)
+	if !ok {
+		return fmt.Errorf("%w: %T is not a proto message", UnsupportedValue, v)
+	}
+	return proto.Unmarshal(data, msg)
+}
+
+type XMLCodec struct{}
+
+func (XMLCodec) ContentType() string {
+	return "application/xml"
+}
+
+func (XMLCodec) Marshal(v any) ([]byte, error) {
+	data, err := xml.Marshal(v)
+	var unsupported *xml.UnsupportedTypeError
+	if errors.As(err, &unsupported) {
+		return nil, fmt.Errorf("%w: %s", UnsupportedValue, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), data...), nil
+}
+
+func (XMLCodec) Unmarshal(data []byte, v any) error {
+	return xml.Unmarshal(data, v)
+}
@@ -12,6 +12,45 @@ type Config struct {
 	WriteTimeout time.Duration `env:"REST_WRITE_TIMEOUT" envDefault:"15s"`
 	ReadTimeout  time.Duration `env:"REST_READ_TIMEOUT" envDefault:"15s"`
 	IdleTimeout  time.Duration `env:"REST_IDLE_TIMEOUT" envDefault:"15s"`
+	// ReadHeaderTimeout and MaxHeaderBytes guard against clients sending headers slowly or endlessly
+	ReadHeaderTimeout time.Duration `env:"REST_READ_HEADER_TIMEOUT" envDefault:"5s"`
+	MaxHeaderBytes    int           `env:"REST_MAX_HEADER_BYTES" envDefault:"1048576"`
+	// MaxBodyBytes and HandlerTimeout are route defaults, zero disables them
+	MaxBodyBytes   int64         `env:"REST_MAX_BODY_BYTES" envDefault:"0"`
+	HandlerTimeout time.Duration `env:"REST_HANDLER_TIMEOUT" envDefault:"0s"`
+	// TimeoutStatus is 503 or 504, sent when a handler misses its deadline
+	TimeoutStatus int  `env:"REST_TIMEOUT_STATUS" envDefault:"504"`
+	ListRoutes    bool `env:"REST_LIST_ROUTES" envDefault:"true"`
+	// Prefix is joined with API versions, routes are served under /api/v1 by default
+	Prefix         string `env:"REST_PREFIX" envDefault:"/api"`
+	DefaultVersion string `env:"REST_DEFAULT_VERSION" envDefault:"v1"`
+	OpenAPI        OpenAPIConfig
+	Errors         ErrorsConfig
+	Cors           Cors
+}
+
+type ErrorsConfig struct {
+	// Format is legacy for {"error": ...} bodies or problem for application/problem+json
+	Format string `env:"REST_ERROR_FORMAT" envDefault:"legacy"`
+	// TypeBase prefixes error codes to build the problem type URI, about:blank is used when empty
+	TypeBase string `env:"REST_ERROR_TYPE_BASE"`
+}
+
+type OpenAPIConfig struct {
+	// Path serves the generated document on the REST port, e.g. /openapi.json,
+	// empty disables it together with the UI so that routes are not listed publicly by default
+	Path        string `env:"REST_OPENAPI_PATH" envDefault:""`
+	UIPath      string `env:"REST_OPENAPI_UI_PATH" envDefault:"/docs"`
+	Title       string `env:"REST_OPENAPI_TITLE" envDefault:"API"`
+	Version     string `env:"REST_OPENAPI_VERSION" envDefault:"1.0.0"`
+	Description string `env:"REST_OPENAPI_DESCRIPTION"`
+}
+
+func (c *Config) version() string {
+	if c.DefaultVersion == "" {
+		return "v1"
+	}
+	return c.DefaultVersion
 }
 
 func (c *Config) BindAddress() string {
@@ -0,0 +1,232 @@
+package rest
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+const anyOrigin = "*"
+
+// Cors configures cross-origin requests, an empty Origins list disables CORS headers
+type Cors struct {
+	// Origins are exact origins, patterns with * wildcards like https://*.example.com,
+	// regular expressions starting with ^ or * for any origin
+	Origins []string `env:"REST_CORS_ORIGINS" envSeparator:"," envDefault:"*"`
+	// Headers allowed in requests, headers asked by the preflight are allowed when empty
+	Headers        []string      `env:"REST_CORS_HEADERS" envSeparator:"," envDefault:"Accept,Content-Type,Content-Length,Accept-Encoding,X-CSRF-Token,Authorization,Cache-Control,X-header"`
+	ExposedHeaders []string      `env:"REST_CORS_EXPOSED_HEADERS" envSeparator:","`
+	Credentials    bool          `env:"REST_CORS_CREDENTIALS" envDefault:"false"`
+	MaxAge         time.Duration `env:"REST_CORS_MAX_AGE" envDefault:"0s"`
+}
+
+// CorsApi is implemented by an Api overriding the global CORS config for prefixes of its RouteRestMap
+type CorsApi interface {
+	CorsRest() map[string]*Cors
+}
+
+type corsPolicy struct {
+	config  Cors
+	any     bool
+	origins map[string]bool
+	regexps []*regexp.Regexp
+	headers string
+	exposed string
+	maxAge  string
+}
+
+func newCorsPolicy(config Cors) (*corsPolicy, error) {
+	p := &corsPolicy{
+		config:  config,
+		origins: make(map[string]bool, len(config.Origins)),
+		headers: strings.Join(config.Headers, ", "),
+		exposed: strings.Join(config.ExposedHeaders, ", "),
+	}
+	if config.MaxAge > 0 {
+		p.maxAge = strconv.Itoa(int(config.MaxAge.Seconds()))
+	}
+
+	for _, origin := range config.Origins {
+		origin = strings.TrimSpace(origin)
+		switch {
+		case origin == anyOrigin:
+			p.any = true
+		case strings.HasPrefix(origin, "^"):
+			re, err := regexp.Compile(origin)
+			if err != nil {
+				return nil, fmt.Errorf("cors origin %q: %w", origin, err)
+			}
+			p.regexps = append(p.regexps, re)
+		case strings.Contains(origin, "*"):
+			pattern := "^" + strings.ReplaceAll(regexp.QuoteMeta(origin), `\*`, `[^/]*`) + "$"
+			p.regexps = append(p.regexps, regexp.MustCompile(pattern))
+		case origin != "":
+			p.origins[strings.ToLower(origin)] = true
+		}
+	}
+	return p, nil
+}
+
+func (p *corsPolicy) allowed(origin string) bool {
+	if p.any {
+		return true
+	}
+	if p.origins[strings.ToLower(origin)] {
+		return true
+	}
+	for _, re := range p.regexps {
+		if re.MatchString(origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// allowOrigin writes the headers shared by preflight and actual requests, false when origin is not allowed
+func (p *corsPolicy) allowOrigin(w http.ResponseWriter, origin string) bool {
+	header := w.Header()
+	if p.any && !p.config.Credentials {
+		header.Set("Access-Control-Allow-Origin", anyOrigin)
+		return true
+	}
+
+	header.Add("Vary", "Origin")
+	if origin == "" || !p.allowed(origin) {
+		return false
+	}
+	header.Set("Access-Control-Allow-Origin", origin)
+	if p.config.Credentials {
+		header.Set("Access-Control-Allow-Credentials", "true")
+	}
+	return true
+}
+
+func (p *corsPolicy) actual(w http.ResponseWriter, r *http.Request) {
+	if p.allowOrigin(w, r.Header.Get("Origin")) && p.exposed != "" {
+		w.Header().Set("Access-Control-Expose-Headers", p.exposed)
+	}
+}
+
+func (p *corsPolicy) preflight(w http.ResponseWriter, r *http.Request, methods string) {
+	header := w.Header()
+	header.Add("Vary", "Access-Control-Request-Method")
+	header.Add("Vary", "Access-Control-Request-Headers")
+	if !p.allowOrigin(w, r.Header.Get("Origin")) {
+		return
+	}
+
+	header.Set("Access-Control-Allow-Methods", methods)
+	if p.headers != "" {
+		header.Set("Access-Control-Allow-Headers", p.headers)
+	} else if requested := r.Header.Get("Access-Control-Request-Headers"); requested != "" {
+		header.Set("Access-Control-Allow-Headers", requested)
+	}
+	if p.maxAge != "" {
+		header.Set("Access-Control-Max-Age", p.maxAge)
+	}
+}
+
+// cors resolves policies of REST routes and answers preflight requests for their paths
+type cors struct {
+	global   *corsPolicy
+	compiled map[*Cors]*corsPolicy
+	routes   map[*mux.Route]*corsPolicy
+	paths    map[string]*preflight
+	order    []string
+}
+
+func newCors(config Cors) (*cors, error) {
+	global, err := newCorsPolicy(config)
+	if err != nil {
+		return nil, err
+	}
+	return &cors{
+		global:   global,
+		compiled: make(map[*Cors]*corsPolicy),
+		routes:   make(map[*mux.Route]*corsPolicy),
+		paths:    make(map[string]*preflight),
+	}, nil
+}
+
+// add registers the route policy, the route config wins over the prefix one and both over the global one
+func (c *cors) add(route *mux.Route, path string, methods []string, configs ...*Cors) error {
+	policy := c.global
+	for _, config := range configs {
+		if config == nil {
+			continue
+		}
+		compiled, ok := c.compiled[config]
+		if !ok {
+			var err error
+			compiled, err = newCorsPolicy(*config)
+			if err != nil {
+				return err
+			}
+			c.compiled[config] = compiled
+		}
+		policy = compiled
+		break
+	}
+	c.routes[route] = policy
+
+	p, ok := c.paths[path]
+	if !ok {
+		p = &preflight{policies: make(map[string]*corsPolicy)}
+		c.paths[path] = p
+		c.order = append(c.order, path)
+	}
+	for _, method := range methods {
+		p.policies[method] = policy
+	}
+	return nil
+}
+
+// Middleware adds CORS headers to responses of REST routes, including errors written by other middlewares
+func (c *cors) Middleware(next http.Handler) http.Handler {
+	f := func(w http.ResponseWriter, r *http.Request) {
+		policy, ok := c.routes[mux.CurrentRoute(r)]
+		if !ok {
+			policy = c.global
+		}
+		policy.actual(w, r)
+		next.ServeHTTP(w, r)
+	}
+	return http.HandlerFunc(f)
+}
+
+// register adds OPTIONS handlers for every path with a REST route
+func (c *cors) register(router *mux.Router) {
+	for _, path := range c.order {
+		p := c.paths[path]
+		if _, ok := p.policies[http.MethodOptions]; ok {
+			continue
+		}
+		methods := make([]string, 0, len(p.policies)+1)
+		for method := range p.policies {
+			methods = append(methods, method)
+		}
+		sort.Strings(methods)
+		p.methods = strings.Join(append(methods, http.MethodOptions), ", ")
+		router.Handle(path, p).Methods(http.MethodOptions)
+	}
+}
+
+type preflight struct {
+	policies map[string]*corsPolicy
+	methods  string
+}
+
+func (p *preflight) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Allow", p.methods)
+	method := r.Header.Get("Access-Control-Request-Method")
+	if policy, ok := p.policies[method]; ok && r.Header.Get("Origin") != "" {
+		policy.preflight(w, r, p.methods)
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
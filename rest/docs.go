@@ -0,0 +1,57 @@
+package rest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	swaggerFiles "github.com/swaggo/files/v2"
+)
+
+// swaggerInitializer replaces the bundled petstore one, validatorUrl is off to keep the UI offline
+const swaggerInitializer = `window.onload = function() {
+  window.ui = SwaggerUIBundle({
+    url: %s,
+    dom_id: '#swagger-ui',
+    deepLinking: true,
+    validatorUrl: null,
+    presets: [
+      SwaggerUIBundle.presets.apis,
+      SwaggerUIStandalonePreset
+    ],
+    plugins: [
+      SwaggerUIBundle.plugins.DownloadUrl
+    ],
+    layout: "StandaloneLayout"
+  });
+};
+`
+
+func openAPIHandler(doc []byte) http.Handler {
+	f := func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", jsonContentType)
+		_, _ = w.Write(doc)
+	}
+	return http.HandlerFunc(f)
+}
+
+// openAPIUIHandler serves the embedded Swagger UI under uiPath reading the document from specPath
+func openAPIUIHandler(uiPath, specPath string) http.Handler {
+	url, _ := json.Marshal(specPath)
+	initializer := fmt.Sprintf(swaggerInitializer, url)
+	files := http.StripPrefix(uiPath+"/", http.FileServer(http.FS(swaggerFiles.FS)))
+
+	f := func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case uiPath:
+			http.Redirect(w, r, uiPath+"/", http.StatusMovedPermanently)
+		case uiPath + "/swagger-initializer.js":
+			w.Header().Set("Content-Type", "text/javascript; charset=utf-8")
+			_, _ = io.WriteString(w, initializer)
+		default:
+			files.ServeHTTP(w, r)
+		}
+	}
+	return http.HandlerFunc(f)
+}
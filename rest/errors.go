@@ -0,0 +1,329 @@
+package rest
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"sync"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	ErrorFormatLegacy  = "legacy"
+	ErrorFormatProblem = "problem"
+	problemContentType = "application/problem+json"
+	validationCode     = "validation_failed"
+)
+
+var InvalidErrorFormat = errors.New("invalid error format")
+
+// Error is a domain error shared by REST and gRPC, Status is the HTTP status it is served with
+type Error struct {
+	Status    int
+	Code      string
+	Message   string
+	Details   any
+	Retryable bool
+	Err       error
+}
+
+func NewError(status int, code, message string) *Error {
+	return &Error{Status: status, Code: code, Message: message}
+}
+
+func (e *Error) Error() string {
+	switch {
+	case e.Message != "":
+		return e.Message
+	case e.Err != nil:
+		return e.Err.Error()
+	default:
+		return http.StatusText(e.Status)
+	}
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is matches errors with the same code so copies made by Wrap and WithDetails match their sentinel
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code != "" && t.Code == e.Code
+}
+
+// Wrap returns a copy of e caused by err
+func (e *Error) Wrap(err error) *Error {
+	c := *e
+	c.Err = err
+	return &c
+}
+
+func (e *Error) WithDetails(details any) *Error {
+	c := *e
+	c.Details = details
+	return &c
+}
+
+// GRPCStatus is used by grpc to send e with the code matching its HTTP status
+func (e *Error) GRPCStatus() *status.Status {
+	st := status.New(GrpcCode(e.Status), e.Error())
+	if e.Code == "" {
+		return st
+	}
+	info := &errdetails.ErrorInfo{
+		Reason:   e.Code,
+		Metadata: map[string]string{"retryable": strconv.FormatBool(e.Retryable)},
+	}
+	if withDetails, err := st.WithDetails(info); err == nil {
+		return withDetails
+	}
+	return st
+}
+
+// GrpcCode maps an HTTP status to the gRPC code with the same meaning
+func GrpcCode(httpStatus int) codes.Code {
+	switch httpStatus {
+	case http.StatusBadRequest, http.StatusUnprocessableEntity:
+		return codes.InvalidArgument
+	case http.StatusUnauthorized:
+		return codes.Unauthenticated
+	case http.StatusForbidden:
+		return codes.PermissionDenied
+	case http.StatusNotFound:
+		return codes.NotFound
+	case http.StatusConflict:
+		return codes.AlreadyExists
+	case http.StatusPreconditionFailed:
+		return codes.FailedPrecondition
+	case http.StatusRequestEntityTooLarge, http.StatusTooManyRequests:
+		return codes.ResourceExhausted
+	case 499:
+		return codes.Canceled
+	case http.StatusNotImplemented:
+		return codes.Unimplemented
+	case http.StatusServiceUnavailable:
+		return codes.Unavailable
+	case http.StatusGatewayTimeout:
+		return codes.DeadlineExceeded
+	}
+	switch {
+	case httpStatus >= 200 && httpStatus < 300:
+		return codes.OK
+	case httpStatus >= 400 && httpStatus < 500:
+		return codes.FailedPrecondition
+	case httpStatus >= 500:
+		return codes.Internal
+	default:
+		return codes.Unknown
+	}
+}
+
+// ErrorMapFunc converts a domain error, nil means the error is not recognised
+type ErrorMapFunc func(err error) *Error
+
+// ErrorMapper converts domain errors returned by REST handlers and gRPC methods into Error
+type ErrorMapper struct {
+	mu    *sync.RWMutex
+	funcs []ErrorMapFunc
+}
+
+func NewErrorMapper(funcs ...ErrorMapFunc) *ErrorMapper {
+	return &ErrorMapper{
+		mu:    &sync.RWMutex{},
+		funcs: funcs,
+	}
+}
+
+// Add appends mapping functions, the first one recognising an error wins
+func (m *ErrorMapper) Add(funcs ...ErrorMapFunc) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.funcs = append(m.funcs, funcs...)
+}
+
+// Is maps errors matching target with errors.Is to e
+func (m *ErrorMapper) Is(target error, e *Error) {
+	m.Add(func(err error) *Error {
+		if errors.Is(err, target) {
+			return e.Wrap(err)
+		}
+		return nil
+	})
+}
+
+// MapAs maps errors of type T found with errors.As
+func MapAs[T error](m *ErrorMapper, f func(target T) *Error) {
+	m.Add(func(err error) *Error {
+		var target T
+		if errors.As(err, &target) {
+			return f(target)
+		}
+		return nil
+	})
+}
+
+// Map returns the Error carried by err or produced by the mapping functions, nil when none applies
+func (m *ErrorMapper) Map(err error) *Error {
+	if err == nil {
+		return nil
+	}
+	var e *Error
+	if errors.As(err, &e) {
+		return e
+	}
+	if m == nil {
+		return builtinError(err)
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, f := range m.funcs {
+		if e = f(err); e != nil {
+			if e.Err == nil {
+				// copied, mappers return shared sentinels
+				return e.Wrap(err)
+			}
+			return e
+		}
+	}
+	return builtinError(err)
+}
+
+// builtinError maps errors of the standard library caused by server limits
+func builtinError(err error) *Error {
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		return RequestTooLarge.Wrap(err)
+	}
+	return nil
+}
+
+func (m *ErrorMapper) grpcError(err error) error {
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+	if e := m.Map(err); e != nil {
+		return e
+	}
+	return err
+}
+
+// UnaryInterceptor returns mapped errors so that they reach clients with their gRPC code
+func (m *ErrorMapper) UnaryInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		resp, err := handler(ctx, req)
+		if err != nil {
+			err = m.grpcError(err)
+		}
+		return resp, err
+	}
+}
+
+func (m *ErrorMapper) StreamInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		err := handler(srv, ss)
+		if err != nil {
+			err = m.grpcError(err)
+		}
+		return err
+	}
+}
+
+// Problem is an RFC 9457 problem details body
+type Problem struct {
+	Type      string       `json:"type" xml:"type"`
+	Title     string       `json:"title" xml:"title"`
+	Status    int          `json:"status" xml:"status"`
+	Detail    string       `json:"detail,omitempty" xml:"detail,omitempty"`
+	Instance  string       `json:"instance,omitempty" xml:"instance,omitempty"`
+	Code      string       `json:"code,omitempty" xml:"code,omitempty"`
+	Details   any          `json:"details,omitempty" xml:"-"`
+	Retryable bool         `json:"retryable" xml:"retryable"`
+	Fields    []FieldError `json:"fields,omitempty" xml:"fields>field,omitempty"`
+}
+
+type errorsKey struct{}
+
+type errorRenderer struct {
+	config ErrorsConfig
+	mapper *ErrorMapper
+}
+
+// resolve returns the status and body served for err returned with code by a handler
+func (e *errorRenderer) resolve(r *http.Request, code int, err error) (int, any) {
+	mapped := e.mapper.Map(err)
+	if mapped != nil && mapped.Status != 0 {
+		code = mapped.Status
+	}
+	if code == 0 {
+		code = http.StatusInternalServerError
+	}
+
+	if e.config.Format != ErrorFormatProblem {
+		if mapped != nil {
+			return code, ErrorResponse{Error: mapped.Error()}
+		}
+		return code, errorResponse(err)
+	}
+
+	problem := Problem{
+		Type:     "about:blank",
+		Title:    http.StatusText(code),
+		Status:   code,
+		Detail:   err.Error(),
+		Instance: r.URL.Path,
+	}
+	var validationErr *ValidationError
+	switch {
+	case mapped != nil:
+		problem.Detail = mapped.Error()
+		problem.Code = mapped.Code
+		problem.Details = mapped.Details
+		problem.Retryable = mapped.Retryable
+	case errors.As(err, &validationErr):
+		problem.Detail = "validation failed"
+		problem.Code = validationCode
+		problem.Fields = validationErr.Fields
+	}
+	if problem.Code != "" && e.config.TypeBase != "" {
+		problem.Type = e.config.TypeBase + problem.Code
+	}
+	return code, problem
+}
+
+func (e *errorRenderer) write(w http.ResponseWriter, r *http.Request, code int, err error) int {
+	code, body := e.resolve(r, code, err)
+	codec, data, err := encode(r, body, true)
+	if err != nil {
+		w.WriteHeader(code)
+		return code
+	}
+	w.Header().Set("Content-Type", e.contentType(codec))
+	w.WriteHeader(code)
+	_, _ = w.Write(data)
+	return code
+}
+
+// WriteError renders err like the errors returned by handlers, with the configured format
+// and the negotiated codec, middlewares answering requests themselves use it. It returns the status sent
+func WriteError(w http.ResponseWriter, r *http.Request, code int, err error) int {
+	renderer, ok := r.Context().Value(errorsKey{}).(*errorRenderer)
+	if !ok {
+		renderer = &errorRenderer{config: ErrorsConfig{Format: ErrorFormatLegacy}}
+	}
+	return renderer.write(w, r, code, err)
+}
+
+// contentType returns the media type of an error body encoded by codec
+func (e *errorRenderer) contentType(codec Codec) string {
+	if e.config.Format == ErrorFormatProblem && codec.ContentType() == jsonContentType {
+		return problemContentType
+	}
+	return codec.ContentType()
+}
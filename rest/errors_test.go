@@ -0,0 +1,37 @@
+package rest
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+)
+
+type quotaError struct {
+	user string
+}
+
+func (e *quotaError) Error() string {
+	return "quota exceeded for " + e.user
+}
+
+func TestMapKeepsSentinels(t *testing.T) {
+	exceeded := NewError(http.StatusTooManyRequests, "quota_exceeded", "quota exceeded")
+	mapper := NewErrorMapper()
+	MapAs(mapper, func(*quotaError) *Error {
+		return exceeded
+	})
+
+	first, second := &quotaError{user: "alice"}, &quotaError{user: "bob"}
+	mappedFirst := mapper.Map(first)
+	mappedSecond := mapper.Map(second)
+
+	if exceeded.Err != nil {
+		t.Errorf("the sentinel was changed to wrap %v", exceeded.Err)
+	}
+	if !errors.Is(mappedFirst.Err, first) || !errors.Is(mappedSecond.Err, second) {
+		t.Errorf("got causes %v and %v", mappedFirst.Err, mappedSecond.Err)
+	}
+	if mappedFirst.Status != http.StatusTooManyRequests || mappedFirst.Code != "quota_exceeded" {
+		t.Errorf("got %+v", mappedFirst)
+	}
+}
@@ -0,0 +1,186 @@
+package rest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	RequestTooLarge      = NewError(http.StatusRequestEntityTooLarge, "request_too_large", "request body is too large")
+	HandlerTimeout       = &Error{Status: http.StatusGatewayTimeout, Code: "handler_timeout", Message: "request handling timed out", Retryable: true}
+	InvalidTimeoutStatus = errors.New("timeout status must be 503 or 504")
+)
+
+// routeLimits resolves the body limit and handler deadline of route, zero means none
+func (c *Config) routeLimits(route *RouteRest) (int64, time.Duration) {
+	maxBody, timeout := c.MaxBodyBytes, c.HandlerTimeout
+	if route.MaxBodyBytes != 0 {
+		maxBody = route.MaxBodyBytes
+	}
+	if route.Timeout != 0 {
+		timeout = route.Timeout
+	}
+	return max(maxBody, 0), max(timeout, 0)
+}
+
+func (c *Config) timeoutStatus() (int, error) {
+	switch c.TimeoutStatus {
+	case 0:
+		return http.StatusGatewayTimeout, nil
+	case http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return c.TimeoutStatus, nil
+	default:
+		return 0, fmt.Errorf("%w: %d", InvalidTimeoutStatus, c.TimeoutStatus)
+	}
+}
+
+// BodyLimit answers 413 to bodies larger than limit, reads past it fail with RequestTooLarge
+func (m *Middlewares) BodyLimit(limit int64, next http.Handler) http.Handler {
+	f := func(w http.ResponseWriter, r *http.Request) {
+		if r.ContentLength > limit {
+			m.logError(r, m.writeError(w, r, http.StatusRequestEntityTooLarge, RequestTooLarge), RequestTooLarge)
+			return
+		}
+		if r.Body != nil && r.Body != http.NoBody {
+			r.Body = http.MaxBytesReader(w, r.Body, limit)
+		}
+		next.ServeHTTP(w, r)
+	}
+	return http.HandlerFunc(f)
+}
+
+type controllerKey struct{}
+
+// withResponseController lets TimeoutMiddleware set the read deadline of the connection
+func withResponseController(next http.Handler) http.Handler {
+	f := func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), controllerKey{}, http.NewResponseController(w))
+		next.ServeHTTP(w, r.WithContext(ctx))
+	}
+	return http.HandlerFunc(f)
+}
+
+type handlerResult struct {
+	res   any
+	code  int
+	err   error
+	panic any
+}
+
+// TimeoutMiddleware cancels the request context after timeout and answers with status
+// without waiting for the handler. Handlers must return once the context is cancelled,
+// the request body fails reads after the deadline and panics raised later are only logged
+func (m *Middlewares) TimeoutMiddleware(hf HandlerFuncRest, timeout time.Duration, status int) HandlerFuncRest {
+	if timeout <= 0 {
+		return hf
+	}
+	timeoutErr := HandlerTimeout
+	if status != timeoutErr.Status {
+		timeoutErr = &Error{Status: status, Code: HandlerTimeout.Code, Message: HandlerTimeout.Message, Retryable: true}
+	}
+
+	f := func(r *http.Request) (any, int, error) {
+		ctx, cancel := context.WithTimeout(r.Context(), timeout)
+		defer cancel()
+
+		// the handler gets its own body so that it never reads r.Body once the request is answered
+		body := &deadlineBody{mu: &sync.Mutex{}, body: r.Body}
+		req := r.WithContext(ctx)
+		if r.Body != nil && r.Body != http.NoBody {
+			req.Body = body
+		}
+
+		// claimed is set by whoever handles the handler outcome first
+		claimed := &atomic.Bool{}
+		done := make(chan handlerResult, 1)
+		go func() {
+			result := handlerResult{}
+			defer func() {
+				result.panic = recover()
+				if claimed.CompareAndSwap(false, true) {
+					done <- result
+					return
+				}
+				if result.panic != nil {
+					m.logger.Error().
+						Str("method", r.Method).
+						Str("url", r.RequestURI).
+						Str("panic", fmt.Sprint(result.panic)).
+						Msg(string(debug.Stack()))
+				}
+			}()
+			result.res, result.code, result.err = hf(req)
+		}()
+
+		var result handlerResult
+		select {
+		case result = <-done:
+		case <-ctx.Done():
+			if claimed.CompareAndSwap(false, true) {
+				// checked first, the failed read below cancels the request context
+				timedOut := errors.Is(ctx.Err(), context.DeadlineExceeded) && r.Context().Err() == nil
+				if controller, ok := r.Context().Value(controllerKey{}).(*http.ResponseController); ok {
+					// fails a read blocked on a slow client so that close does not wait for it
+					_ = controller.SetReadDeadline(time.Now())
+				}
+				body.close()
+				if timedOut {
+					return nil, status, timeoutErr
+				}
+				// the client is gone, the response is not read
+				return nil, http.StatusServiceUnavailable, ctx.Err()
+			}
+			result = <-done
+		}
+
+		if result.panic != nil {
+			// repanic in the request goroutine so that RecoveryMiddleware handles it
+			panic(result.panic)
+		}
+		if result.err != nil && errors.Is(result.err, context.DeadlineExceeded) && ctx.Err() != nil {
+			return nil, status, timeoutErr.Wrap(result.err)
+		}
+		return result.res, result.code, result.err
+	}
+	return f
+}
+
+// deadlineBody serializes reads of a request body with close, reads fail once it is closed
+type deadlineBody struct {
+	mu     *sync.Mutex
+	body   io.ReadCloser
+	closed bool
+}
+
+func (b *deadlineBody) Read(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return 0, http.ErrBodyReadAfterClose
+	}
+	return b.body.Read(p)
+}
+
+func (b *deadlineBody) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return nil
+	}
+	b.closed = true
+	return b.body.Close()
+}
+
+// close waits for a pending read, TimeoutMiddleware unblocks it through the connection read deadline
+func (b *deadlineBody) close() {
+	b.mu.Lock()
+	b.closed = true
+	b.mu.Unlock()
+}
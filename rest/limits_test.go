@@ -0,0 +1,237 @@
+package rest
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/DoomLordor/logger"
+)
+
+// syncBuffer collects log lines written by handler goroutines
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func timeoutServer(t *testing.T, m *Middlewares, hf HandlerFuncRest, timeout time.Duration) *httptest.Server {
+	t.Helper()
+	handler := m.RecoveryMiddleware(withResponseController(m.HandleWrapper(m.TimeoutMiddleware(hf, timeout, http.StatusGatewayTimeout))))
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestTimeoutSlowBody(t *testing.T) {
+	m := NewMiddlewares(nil, logger.NewLogger("test"), nil)
+	readErr := make(chan error, 1)
+	server := timeoutServer(t, m, func(r *http.Request) (any, int, error) {
+		_, err := io.ReadAll(r.Body)
+		readErr <- err
+		return nil, http.StatusNoContent, err
+	}, 100*time.Millisecond)
+
+	// the client sends a part of the body and stalls
+	body, writer := io.Pipe()
+	defer writer.Close()
+	go func() {
+		_, _ = writer.Write([]byte(`{"name":`))
+	}()
+
+	start := time.Now()
+	resp, err := http.Post(server.URL, jsonContentType, body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusGatewayTimeout {
+		t.Errorf("got status %d, want 504", resp.StatusCode)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("answered after %s, the pending read delayed the timeout", elapsed)
+	}
+	select {
+	case err := <-readErr:
+		if err == nil {
+			t.Error("the handler read the body after its deadline")
+		}
+	case <-time.After(time.Second):
+		t.Error("the handler read was not unblocked")
+	}
+}
+
+func TestTimeoutLatePanic(t *testing.T) {
+	logs := &syncBuffer{}
+	err := logger.InitLogger(logs, logger.Config{LogLevel: "info", LogJson: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := NewMiddlewares(nil, logger.NewLogger("test"), nil)
+
+	panicked := make(chan struct{})
+	server := timeoutServer(t, m, func(r *http.Request) (any, int, error) {
+		<-r.Context().Done()
+		// lets the middleware answer first, a panic racing the deadline may be rethrown
+		time.Sleep(50 * time.Millisecond)
+		defer close(panicked)
+		panic("late failure")
+	}, 50*time.Millisecond)
+
+	resp, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusGatewayTimeout {
+		t.Errorf("got status %d, want 504", resp.StatusCode)
+	}
+
+	<-panicked
+	deadline := time.Now().Add(time.Second)
+	for !strings.Contains(logs.String(), "late failure") && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if !strings.Contains(logs.String(), "late failure") {
+		t.Errorf("the late panic was not logged: %s", logs.String())
+	}
+}
+
+func TestTimeoutOutcomes(t *testing.T) {
+	m := NewMiddlewares(nil, logger.NewLogger("test"), nil)
+	tests := []struct {
+		name string
+		hf   HandlerFuncRest
+		code int
+	}{
+		{
+			name: "in time",
+			hf: func(r *http.Request) (any, int, error) {
+				return "ok", http.StatusOK, nil
+			},
+			code: http.StatusOK,
+		},
+		{
+			name: "early panic",
+			hf: func(r *http.Request) (any, int, error) {
+				panic("early failure")
+			},
+			code: http.StatusInternalServerError,
+		},
+		{
+			name: "deadline error",
+			hf: func(r *http.Request) (any, int, error) {
+				<-r.Context().Done()
+				return nil, http.StatusInternalServerError, r.Context().Err()
+			},
+			code: http.StatusGatewayTimeout,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := timeoutServer(t, m, tt.hf, 50*time.Millisecond)
+			resp, err := http.Get(server.URL)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != tt.code {
+				t.Errorf("got status %d, want %d", resp.StatusCode, tt.code)
+			}
+		})
+	}
+}
+
+func TestDeadlineBody(t *testing.T) {
+	body := &deadlineBody{mu: &sync.Mutex{}, body: io.NopCloser(strings.NewReader("data"))}
+	if err := body.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := body.Read(make([]byte, 4)); !errors.Is(err, http.ErrBodyReadAfterClose) {
+		t.Errorf("got %v reading after Close", err)
+	}
+
+	body = &deadlineBody{mu: &sync.Mutex{}, body: io.NopCloser(strings.NewReader("data"))}
+	body.close()
+	if _, err := body.Read(make([]byte, 4)); !errors.Is(err, http.ErrBodyReadAfterClose) {
+		t.Errorf("got %v reading after the deadline", err)
+	}
+}
+
+func TestRouteLimits(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  Config
+		route   RouteRest
+		maxBody int64
+		timeout time.Duration
+	}{
+		{name: "no limits by default", config: Config{}},
+		{name: "server limits", config: Config{MaxBodyBytes: 1024, HandlerTimeout: time.Second}, maxBody: 1024, timeout: time.Second},
+		{name: "route overrides", config: Config{MaxBodyBytes: 1024, HandlerTimeout: time.Second}, route: RouteRest{MaxBodyBytes: 10, Timeout: time.Minute}, maxBody: 10, timeout: time.Minute},
+		{name: "route disables", config: Config{MaxBodyBytes: 1024, HandlerTimeout: time.Second}, route: RouteRest{MaxBodyBytes: -1, Timeout: -1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			maxBody, timeout := tt.config.routeLimits(&tt.route)
+			if maxBody != tt.maxBody || timeout != tt.timeout {
+				t.Errorf("got %d %s, want %d %s", maxBody, timeout, tt.maxBody, tt.timeout)
+			}
+		})
+	}
+}
+
+func TestBodyLimit(t *testing.T) {
+	m := NewMiddlewares(nil, logger.NewLogger("test"), nil)
+	typed := Typed(func(_ context.Context, req codecRequest) (codecResponse, int, error) {
+		return codecResponse{Name: req.Name}, http.StatusOK, nil
+	})
+	handler := m.BodyLimit(16, m.HandleWrapper(typed))
+
+	tests := []struct {
+		name    string
+		body    string
+		chunked bool
+		code    int
+	}{
+		{name: "within limit", body: `{"name":"box"}`, code: http.StatusOK},
+		{name: "declared too large", body: `{"name":"a long name"}`, code: http.StatusRequestEntityTooLarge},
+		{name: "read too large", body: `{"name":"a long name"}`, chunked: true, code: http.StatusRequestEntityTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/items", strings.NewReader(tt.body))
+			r.Header.Set("Content-Type", jsonContentType)
+			if tt.chunked {
+				r.ContentLength = -1
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+			if w.Code != tt.code {
+				t.Errorf("got status %d, want %d: %s", w.Code, tt.code, w.Body.String())
+			}
+		})
+	}
+}
@@ -6,15 +6,18 @@ import (
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/DoomLordor/go-apiserver/metrics"
 )
 
 type Prometheus struct {
 	requestCount  *prometheus.CounterVec
 	responseCount *prometheus.CounterVec
 	latency       *prometheus.HistogramVec
+	deprecated    *prometheus.CounterVec
 }
 
-func NewPrometheusService() (*Prometheus, error) {
+func NewPrometheusService(registerer prometheus.Registerer) (*Prometheus, error) {
 	requestCount := prometheus.NewCounterVec(
 		prometheus.CounterOpts{
 			Name: "total_request",
@@ -40,27 +43,41 @@ func NewPrometheusService() (*Prometheus, error) {
 		[]string{"path"},
 	)
 
-	s := &Prometheus{
-		requestCount:  requestCount,
-		responseCount: responseCount,
-		latency:       latency,
+	deprecated := prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "deprecated_requests_total",
+			Help: "Total number of HTTP requests to deprecated routes",
+		},
+		[]string{"path", "method"},
+	)
+
+	requestCount, err := metrics.Register(registerer, requestCount)
+	if err != nil {
+		return nil, err
 	}
 
-	err := prometheus.Register(s.requestCount)
-	if err != nil && err.Error() != "duplicate metrics collector registration attempted" {
+	responseCount, err = metrics.Register(registerer, responseCount)
+	if err != nil {
 		return nil, err
 	}
 
-	err = prometheus.Register(s.responseCount)
-	if err != nil && err.Error() != "duplicate metrics collector registration attempted" {
+	latency, err = metrics.Register(registerer, latency)
+	if err != nil {
 		return nil, err
 	}
 
-	err = prometheus.Register(s.latency)
-	if err != nil && err.Error() != "duplicate metrics collector registration attempted" {
+	deprecated, err = metrics.Register(registerer, deprecated)
+	if err != nil {
 		return nil, err
 	}
 
+	s := &Prometheus{
+		requestCount:  requestCount,
+		responseCount: responseCount,
+		latency:       latency,
+		deprecated:    deprecated,
+	}
+
 	return s, nil
 }
 
@@ -83,3 +100,13 @@ func (s *Prometheus) RequestMetricsMiddleware(path string, next http.Handler) ht
 	}
 	return http.HandlerFunc(f)
 }
+
+// DeprecationMiddleware announces the deprecation in response headers and counts the calls
+func (s *Prometheus) DeprecationMiddleware(path string, deprecation *Deprecation, next http.Handler) http.Handler {
+	f := func(w http.ResponseWriter, r *http.Request) {
+		s.deprecated.WithLabelValues(path, r.Method).Inc()
+		deprecation.setHeaders(w.Header())
+		next.ServeHTTP(w, r)
+	}
+	return http.HandlerFunc(f)
+}
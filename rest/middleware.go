@@ -2,7 +2,6 @@ package rest
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"io"
 	"net/http"
@@ -11,7 +10,9 @@ import (
 	"sync/atomic"
 	"time"
 
+	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
 	"go.opentelemetry.io/otel/trace"
 
 	"github.com/DoomLordor/logger"
@@ -19,12 +20,21 @@ import (
 )
 
 const (
-	UserKey = "user"
-	bearer  = "Bearer "
+	UserKey      = "user"
+	RequestIdKey = "requestId"
+	bearer       = "Bearer "
 )
 
 type ErrorResponse struct {
-	Error string `json:"error"`
+	Error string `json:"error" xml:"error"`
+}
+
+func errorResponse(err error) any {
+	var validationErr *ValidationError
+	if errors.As(err, &validationErr) {
+		return ValidationErrorResponse{Error: "validation failed", Fields: validationErr.Fields}
+	}
+	return ErrorResponse{Error: err.Error()}
 }
 
 type WsResponse struct {
@@ -34,12 +44,18 @@ type WsResponse struct {
 
 type AuthFunc func(ctx context.Context, token string) (any, error)
 
+// SpanHook is called with the request context once its server span is started
+type SpanHook func(ctx context.Context)
+
 type Middlewares struct {
 	authFunc  AuthFunc
 	logger    *logger.Logger
 	requestId *atomic.Uint64
 	upgrader  *websocket.Upgrader
 	tracer    trace.Tracer
+	codecs    *Codecs
+	errors    *errorRenderer
+	spanHooks []SpanHook
 }
 
 func NewMiddlewares(authFunc AuthFunc, logger *logger.Logger, tracer trace.Tracer) *Middlewares {
@@ -49,17 +65,23 @@ func NewMiddlewares(authFunc AuthFunc, logger *logger.Logger, tracer trace.Trace
 		requestId: &atomic.Uint64{},
 		upgrader:  &websocket.Upgrader{},
 		tracer:    tracer,
+		codecs:    DefaultCodecs(),
+		errors:    &errorRenderer{config: ErrorsConfig{Format: ErrorFormatLegacy}},
 	}
 }
 
+// RequestId returns the last request ID issued
+func (m *Middlewares) RequestId() uint64 {
+	return m.requestId.Load()
+}
+
 func (m *Middlewares) TokenMiddleware(next http.Handler) http.Handler {
 	f := func(w http.ResponseWriter, r *http.Request) {
 		header := r.Header.Get("Authorization")
 		if header == "" {
 			text := "No Authorization Header"
 			m.logger.Warn().Msg(text)
-			w.WriteHeader(http.StatusForbidden)
-			_ = json.NewEncoder(w).Encode(ErrorResponse{Error: text})
+			m.writeError(w, r, http.StatusForbidden, errors.New(text))
 			return
 		}
 
@@ -67,8 +89,7 @@ func (m *Middlewares) TokenMiddleware(next http.Handler) http.Handler {
 		if !found {
 			text := "Invalid token"
 			m.logger.Warn().Msg(text)
-			w.WriteHeader(http.StatusForbidden)
-			_ = json.NewEncoder(w).Encode(ErrorResponse{Error: text})
+			m.writeError(w, r, http.StatusForbidden, errors.New(text))
 			return
 		}
 
@@ -79,8 +100,7 @@ func (m *Middlewares) TokenMiddleware(next http.Handler) http.Handler {
 			next.ServeHTTP(w, r)
 		} else {
 			m.logger.Warn().Msg(err.Error())
-			w.WriteHeader(http.StatusForbidden)
-			_ = json.NewEncoder(w).Encode(ErrorResponse{Error: err.Error()})
+			m.writeError(w, r, http.StatusForbidden, err)
 			return
 		}
 	}
@@ -90,15 +110,23 @@ func (m *Middlewares) TokenMiddleware(next http.Handler) http.Handler {
 func (m *Middlewares) CommonMiddleware(next http.Handler) http.Handler {
 	f := func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Add("Content-Type", "application/json")
-		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "POST, GET, OPTIONS, PUT, PATCH, DELETE")
-		headers := "Accept, Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, Access-Control-Request-Headers, Access-Control-Request-Method, Connection, Host, Origin, User-Agent, Referer, Cache-Control, X-header"
-		w.Header().Set("Access-Control-Allow-Headers", headers)
 		next.ServeHTTP(w, r)
 	}
 	return http.HandlerFunc(f)
 }
 
+// CodecMiddleware negotiates response codecs, handlers encoding or decoding through a codec
+// answer 406 and 415 for unsupported media types while raw handlers are left alone
+func (m *Middlewares) CodecMiddleware(next http.Handler) http.Handler {
+	f := func(w http.ResponseWriter, r *http.Request) {
+		// no acceptable codec leaves response empty, encode answers NotAcceptable then
+		response, _ := m.codecs.Negotiate(r.Header.Get("Accept"))
+		ctx := context.WithValue(r.Context(), codecKey{}, &negotiated{codecs: m.codecs, response: response})
+		next.ServeHTTP(w, r.WithContext(ctx))
+	}
+	return http.HandlerFunc(f)
+}
+
 func (m *Middlewares) TimeMiddleware(next http.Handler) http.Handler {
 	f := func(w http.ResponseWriter, r *http.Request) {
 		requestId := m.requestId.Add(1)
@@ -110,7 +138,8 @@ func (m *Middlewares) TimeMiddleware(next http.Handler) http.Handler {
 			Uint64("requestId", requestId).
 			Msg("Start")
 
-		next.ServeHTTP(writer, r)
+		ctx := context.WithValue(r.Context(), RequestIdKey, requestId)
+		next.ServeHTTP(writer, r.WithContext(ctx))
 		end := time.Now().UnixMilli() - start
 		m.logger.Info().
 			Str("method", r.Method).
@@ -128,8 +157,13 @@ func (m *Middlewares) TracingMiddleware(hf HandlerFuncRest) HandlerFuncRest {
 		return hf
 	}
 	f := func(r *http.Request) (any, int, error) {
-		ctx, span := m.tracer.Start(r.Context(), r.URL.Path)
+		// Obtain parent propagator if exists
+		ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+		ctx, span := m.tracer.Start(ctx, r.URL.Path)
 		defer span.End()
+		for _, hook := range m.spanHooks {
+			hook(ctx)
+		}
 
 		res, code, err := hf(r.WithContext(ctx))
 		if err != nil {
@@ -151,7 +185,8 @@ func (m *Middlewares) LoggingMiddleware(next http.Handler) http.Handler {
 			Uint64("requestId", requestId).
 			Msg("Connect")
 
-		next.ServeHTTP(w, r)
+		ctx := context.WithValue(r.Context(), RequestIdKey, requestId)
+		next.ServeHTTP(w, r.WithContext(ctx))
 		m.logger.Info().
 			Str("url", r.RequestURI).
 			Uint64("requestId", requestId).
@@ -192,31 +227,62 @@ func (m *Middlewares) recover(w http.ResponseWriter) {
 func (m *Middlewares) HandleWrapper(hf HandlerFuncRest) http.Handler {
 	f := func(w http.ResponseWriter, r *http.Request) {
 		res, code, err := hf(r)
-		w.WriteHeader(code)
 		if err != nil {
-			res = ErrorResponse{Error: err.Error()}
-			if code >= http.StatusInternalServerError {
-				m.logger.Err(err).Str("method", r.Method).Str("url", r.RequestURI).Send()
-			} else {
-				m.logger.Warn().
-					Str("method", r.Method).
-					Str("url", r.RequestURI).
-					Str("warning", err.Error()).
-					Send()
-			}
+			m.logError(r, m.writeError(w, r, code, err), err)
+			return
 		}
-		if res != nil {
-			st, ok := res.(string)
-			if ok {
-				_, _ = io.WriteString(w, st)
-				return
-			}
-			_ = json.NewEncoder(w).Encode(res)
+		if res == nil {
+			w.WriteHeader(code)
+			return
 		}
+		if st, ok := res.(string); ok {
+			w.WriteHeader(code)
+			_, _ = io.WriteString(w, st)
+			return
+		}
+
+		codec, data, err := encode(r, res, false)
+		if errors.Is(err, NotAcceptable) {
+			m.logError(r, m.writeError(w, r, http.StatusNotAcceptable, err), err)
+			return
+		}
+		if err != nil {
+			m.logError(r, m.writeError(w, r, http.StatusInternalServerError, err), err)
+			return
+		}
+		w.Header().Set("Content-Type", codec.ContentType())
+		w.WriteHeader(code)
+		_, _ = w.Write(data)
+	}
+	return http.HandlerFunc(f)
+}
+
+// writeError renders err with the configured format and the negotiated codec, returning the status sent
+func (m *Middlewares) writeError(w http.ResponseWriter, r *http.Request, code int, err error) int {
+	return m.errors.write(w, r, code, err)
+}
+
+// ErrorsMiddleware makes the error renderer available to WriteError
+func (m *Middlewares) ErrorsMiddleware(next http.Handler) http.Handler {
+	f := func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), errorsKey{}, m.errors)
+		next.ServeHTTP(w, r.WithContext(ctx))
 	}
 	return http.HandlerFunc(f)
 }
 
+func (m *Middlewares) logError(r *http.Request, code int, err error) {
+	if code >= http.StatusInternalServerError {
+		m.logger.Err(err).Str("method", r.Method).Str("url", r.RequestURI).Send()
+		return
+	}
+	m.logger.Warn().
+		Str("method", r.Method).
+		Str("url", r.RequestURI).
+		Str("warning", err.Error()).
+		Send()
+}
+
 func (m *Middlewares) HandleWsWrapper(hf HandlerFuncWs) http.Handler {
 	f := func(w http.ResponseWriter, r *http.Request) {
 		conn, err := m.upgrader.Upgrade(w, r, nil)
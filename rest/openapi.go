@@ -0,0 +1,255 @@
+package rest
+
+import (
+	"net/http"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	openAPIVersion  = "3.1.0"
+	bearerScheme    = "bearerAuth"
+	jsonContentType = "application/json"
+)
+
+// pathVarPattern matches mux variables dropping their regexp, {id:[0-9]+} becomes {id}
+var pathVarPattern = regexp.MustCompile(`\{([^{}:]+)(:[^{}]*(\{[^{}]*\}[^{}]*)*)?\}`)
+
+type OpenAPI struct {
+	OpenAPI    string              `json:"openapi"`
+	Info       OpenAPIInfo         `json:"info"`
+	Paths      map[string]PathItem `json:"paths"`
+	Components OpenAPIComponents   `json:"components"`
+}
+
+type OpenAPIInfo struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+type OpenAPIComponents struct {
+	Schemas         map[string]*Schema        `json:"schemas,omitempty"`
+	SecuritySchemes map[string]SecurityScheme `json:"securitySchemes,omitempty"`
+}
+
+type SecurityScheme struct {
+	Type   string `json:"type"`
+	Scheme string `json:"scheme"`
+}
+
+// PathItem maps lower case HTTP methods to operations
+type PathItem map[string]*Operation
+
+type Operation struct {
+	OperationId string                `json:"operationId,omitempty"`
+	Summary     string                `json:"summary,omitempty"`
+	Description string                `json:"description,omitempty"`
+	Tags        []string              `json:"tags,omitempty"`
+	Parameters  []Parameter           `json:"parameters,omitempty"`
+	RequestBody *RequestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]*Response  `json:"responses"`
+	Security    []map[string][]string `json:"security,omitempty"`
+	Deprecated  bool                  `json:"deprecated,omitempty"`
+}
+
+type Parameter struct {
+	Name     string  `json:"name"`
+	In       string  `json:"in"`
+	Required bool    `json:"required,omitempty"`
+	Schema   *Schema `json:"schema"`
+}
+
+type RequestBody struct {
+	Required bool                 `json:"required,omitempty"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+type Response struct {
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+type openAPIBuilder struct {
+	doc     *OpenAPI
+	schemas *schemas
+	problem bool
+}
+
+func newOpenAPIBuilder(config OpenAPIConfig, errorsConfig ErrorsConfig) *openAPIBuilder {
+	s := newSchemas()
+	return &openAPIBuilder{
+		doc: &OpenAPI{
+			OpenAPI: openAPIVersion,
+			Info: OpenAPIInfo{
+				Title:       config.Title,
+				Version:     config.Version,
+				Description: config.Description,
+			},
+			Paths: make(map[string]PathItem),
+			Components: OpenAPIComponents{
+				Schemas: s.components,
+			},
+		},
+		schemas: s,
+		problem: errorsConfig.Format == ErrorFormatProblem,
+	}
+}
+
+func (b *openAPIBuilder) add(path string, route *RouteRest) {
+	path = pathVarPattern.ReplaceAllString(path, "{$1}")
+	item, ok := b.doc.Paths[path]
+	if !ok {
+		item = make(PathItem, len(route.Methods))
+		b.doc.Paths[path] = item
+	}
+
+	docs := Docs{}
+	if route.Docs != nil {
+		docs = *route.Docs
+	}
+
+	for _, method := range route.Methods {
+		op := b.operation(path, method, docs)
+		op.Deprecated = route.Deprecation != nil
+		if route.Secure {
+			op.Security = []map[string][]string{{bearerScheme: {}}}
+			b.errorResponse(op, http.StatusForbidden)
+			if b.doc.Components.SecuritySchemes == nil {
+				b.doc.Components.SecuritySchemes = map[string]SecurityScheme{
+					bearerScheme: {Type: "http", Scheme: "bearer"},
+				}
+			}
+		}
+		item[strings.ToLower(method)] = op
+	}
+}
+
+func (b *openAPIBuilder) operation(path, method string, docs Docs) *Operation {
+	op := &Operation{
+		OperationId: docs.OperationId,
+		Summary:     docs.Summary,
+		Description: docs.Description,
+		Tags:        docs.Tags,
+		Responses:   make(map[string]*Response),
+	}
+
+	declared := make(map[string]bool)
+	if docs.Request != nil {
+		t := reflect.TypeOf(docs.Request)
+		for t.Kind() == reflect.Pointer {
+			t = t.Elem()
+		}
+		if t.Kind() == reflect.Struct {
+			op.Parameters, declared = b.parameters(t)
+			body := b.schemas.object(t)
+			if len(body.Properties) != 0 && method != http.MethodGet && method != http.MethodHead {
+				required := len(body.Required) != 0
+				if t.Name() != "" {
+					body = b.schemas.of(t)
+				}
+				op.RequestBody = &RequestBody{Required: required, Content: map[string]MediaType{jsonContentType: {Schema: body}}}
+			}
+		} else {
+			op.RequestBody = &RequestBody{Content: map[string]MediaType{jsonContentType: {Schema: b.schemas.of(t)}}}
+		}
+	}
+
+	for _, match := range pathVarPattern.FindAllStringSubmatch(path, -1) {
+		if !declared[match[1]] {
+			op.Parameters = append(op.Parameters, Parameter{Name: match[1], In: SourcePath, Required: true, Schema: &Schema{Type: "string"}})
+		}
+	}
+
+	status := docs.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	response := &Response{Description: http.StatusText(status)}
+	if docs.Response != nil {
+		response.Content = map[string]MediaType{jsonContentType: {Schema: b.schemas.of(reflect.TypeOf(docs.Response))}}
+	}
+	op.Responses[strconv.Itoa(status)] = response
+
+	if docs.typed {
+		op.Responses[strconv.Itoa(http.StatusBadRequest)] = &Response{
+			Description: http.StatusText(http.StatusBadRequest),
+			Content:     b.errorContent(ValidationErrorResponse{}),
+		}
+	}
+	for _, code := range docs.Errors {
+		b.errorResponse(op, code)
+	}
+	return op
+}
+
+func (b *openAPIBuilder) errorResponse(op *Operation, code int) {
+	key := strconv.Itoa(code)
+	if _, ok := op.Responses[key]; ok {
+		return
+	}
+	op.Responses[key] = &Response{
+		Description: http.StatusText(code),
+		Content:     b.errorContent(ErrorResponse{}),
+	}
+}
+
+// errorContent describes error bodies, legacy describes the legacy shape served without problem format
+func (b *openAPIBuilder) errorContent(legacy any) map[string]MediaType {
+	if b.problem {
+		return map[string]MediaType{problemContentType: {Schema: b.schemas.of(reflect.TypeOf(Problem{}))}}
+	}
+	return map[string]MediaType{jsonContentType: {Schema: b.schemas.of(reflect.TypeOf(legacy))}}
+}
+
+// parameters describes fields bound by Typed from the path, query and headers
+func (b *openAPIBuilder) parameters(t reflect.Type) ([]Parameter, map[string]bool) {
+	params := make([]Parameter, 0)
+	declared := make(map[string]bool)
+	params = b.addParameters(t, params, declared)
+
+	sort.SliceStable(params, func(i, j int) bool {
+		return params[i].In == SourcePath && params[j].In != SourcePath
+	})
+	return params, declared
+}
+
+func (b *openAPIBuilder) addParameters(t reflect.Type, params []Parameter, declared map[string]bool) []Parameter {
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if embedded := embeddedStruct(sf); embedded != nil && sf.IsExported() {
+			params = b.addParameters(embedded, params, declared)
+			continue
+		}
+
+		param := Parameter{}
+		switch {
+		case sf.Tag.Get("path") != "":
+			param = Parameter{Name: sf.Tag.Get("path"), In: SourcePath, Required: true}
+			declared[param.Name] = true
+		case sf.Tag.Get("query") != "":
+			param = Parameter{Name: sf.Tag.Get("query"), In: SourceQuery}
+		case sf.Tag.Get("header") != "":
+			param = Parameter{Name: sf.Tag.Get("header"), In: SourceHeader}
+		default:
+			continue
+		}
+		param.Schema = b.schemas.of(sf.Type)
+		if applyRules(param.Schema, sf) {
+			param.Required = true
+		}
+		params = append(params, param)
+	}
+	return params
+}
+
+func (b *openAPIBuilder) document() *OpenAPI {
+	return b.doc
+}
@@ -3,7 +3,9 @@ package rest
 import (
 	"context"
 	"net/http"
+	"time"
 
+	"github.com/gorilla/mux"
 	"github.com/gorilla/websocket"
 )
 
@@ -15,6 +17,47 @@ type RouteRest struct {
 	Secure      bool
 	Metrics     bool
 	HandlerFunc HandlerFuncRest
+	Docs        *Docs
+	// Cors overrides the prefix and global CORS config for the route
+	Cors        *Cors
+	Deprecation *Deprecation
+	Use         RouteMiddlewares
+	// MaxBodyBytes and Timeout override the server defaults, negative values disable them
+	MaxBodyBytes int64
+	Timeout      time.Duration
+}
+
+// RouteMiddlewares wrap a single route, the first middleware of a list is the outermost.
+// Prefix middlewares of a MiddlewareApi run before the route ones of the same position
+type RouteMiddlewares struct {
+	// BeforeMetrics run first, outside the request metrics and the deprecation headers
+	BeforeMetrics []mux.MiddlewareFunc
+	// BeforeAuth run after the metrics and before the token check of Secure routes
+	BeforeAuth []mux.MiddlewareFunc
+	// AfterAuth run after the token check and the server UseAfterAuth middlewares, next to the handler
+	AfterAuth []mux.MiddlewareFunc
+}
+
+// MiddlewareApi is implemented by an Api adding middlewares to every route of RouteRestMap prefixes
+type MiddlewareApi interface {
+	MiddlewaresRest() map[string]RouteMiddlewares
+}
+
+// Docs describes a route in the generated OpenAPI document
+type Docs struct {
+	Summary     string
+	Description string
+	Tags        []string
+	OperationId string
+	// Request and Response are values of the route types, Request fields tagged
+	// path, query or header become parameters and the others the JSON body
+	Request  any
+	Response any
+	// Status is the success status code, 200 by default
+	Status int
+	// Errors lists error status codes the route answers with
+	Errors []int
+	typed  bool
 }
 
 type RoutesRest []*RouteRest
@@ -26,7 +69,27 @@ type RouteWs struct {
 	Pattern     string
 	Secure      bool
 	HandlerFunc HandlerFuncWs
+	// Use wraps the route, BeforeMetrics and BeforeAuth both run before the token check
+	Use RouteMiddlewares
 }
 
 type RoutesWs []*RouteWs
 type RouteWsMap map[string]RoutesWs
+
+const (
+	RouteTypeRest = "rest"
+	RouteTypeWs   = "ws"
+)
+
+type RouteInfo struct {
+	Type    string   `json:"type"`
+	Prefix  string   `json:"prefix"`
+	Pattern string   `json:"pattern"`
+	Path    string   `json:"path"`
+	Methods []string `json:"methods"`
+	Secure  bool     `json:"secure"`
+	Metrics bool     `json:"metrics"`
+	Version string   `json:"version,omitempty"`
+	// Deprecated is set for REST routes with a Deprecation
+	Deprecated bool `json:"deprecated,omitempty"`
+}
@@ -0,0 +1,199 @@
+package rest
+
+import (
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var schemaNameReplacer = regexp.MustCompile(`[^A-Za-z0-9_.-]+`)
+
+// Schema is the JSON Schema subset used by the generated OpenAPI document
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Enum                 []any              `json:"enum,omitempty"`
+	Default              any                `json:"default,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	MinLength            *float64           `json:"minLength,omitempty"`
+	MaxLength            *float64           `json:"maxLength,omitempty"`
+	MinItems             *float64           `json:"minItems,omitempty"`
+	MaxItems             *float64           `json:"maxItems,omitempty"`
+}
+
+// schemas turns Go types into schemas, named structs are stored once and referenced
+type schemas struct {
+	components map[string]*Schema
+	names      map[reflect.Type]string
+}
+
+func newSchemas() *schemas {
+	return &schemas{
+		components: make(map[string]*Schema),
+		names:      make(map[reflect.Type]string),
+	}
+}
+
+func (s *schemas) of(t reflect.Type) *Schema {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch {
+	case t == timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case t == durationType:
+		return &Schema{Type: "integer", Format: "int64", Description: "duration in nanoseconds"}
+	case t.Kind() != reflect.Struct && reflect.PointerTo(t).Implements(textUnmarshalerType):
+		return &Schema{Type: "string"}
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Int64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		zero := 0.0
+		return &Schema{Type: "integer", Format: "int64", Minimum: &zero}
+	case reflect.Float32:
+		return &Schema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: s.of(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: s.of(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return s.object(t)
+		}
+		return &Schema{Ref: "#/components/schemas/" + s.name(t)}
+	default:
+		return &Schema{}
+	}
+}
+
+func (s *schemas) name(t reflect.Type) string {
+	if name, ok := s.names[t]; ok {
+		return name
+	}
+
+	name := schemaNameReplacer.ReplaceAllString(t.Name(), "_")
+	if _, taken := s.components[name]; taken {
+		pkg := t.PkgPath()
+		pkg = pkg[strings.LastIndex(pkg, "/")+1:]
+		base := schemaNameReplacer.ReplaceAllString(pkg, "_") + "." + name
+		name = base
+		for i := 2; s.components[name] != nil; i++ {
+			name = base + strconv.Itoa(i)
+		}
+	}
+
+	s.names[t] = name
+	s.components[name] = &Schema{}
+	*s.components[name] = *s.object(t)
+	return name
+}
+
+// object describes the JSON body of a struct, fields bound from the path, query or headers are left out
+func (s *schemas) object(t reflect.Type) *Schema {
+	schema := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	s.fields(t, schema)
+	return schema
+}
+
+func (s *schemas) fields(t reflect.Type, schema *Schema) {
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if !sf.IsExported() || sf.Tag.Get("path") != "" || sf.Tag.Get("query") != "" || sf.Tag.Get("header") != "" {
+			continue
+		}
+
+		name := jsonName(sf)
+		if name == "-" {
+			continue
+		}
+		if sf.Anonymous && sf.Tag.Get("json") == "" {
+			embedded := sf.Type
+			if embedded.Kind() == reflect.Pointer {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				s.fields(embedded, schema)
+				continue
+			}
+		}
+
+		property := s.of(sf.Type)
+		if applyRules(property, sf) {
+			schema.Required = append(schema.Required, name)
+		}
+		schema.Properties[name] = property
+	}
+}
+
+// applyRules copies validate rules into schema and reports whether the field is required
+func applyRules(schema *Schema, sf reflect.StructField) bool {
+	rules, err := parseRules(sf.Tag.Get("validate"))
+	if err != nil {
+		return false
+	}
+	numeric := schema.Type == "integer" || schema.Type == "number"
+	if def := sf.Tag.Get("default"); def != "" {
+		schema.Default = def
+		if value, err := strconv.ParseFloat(def, 64); numeric && err == nil {
+			schema.Default = value
+		}
+		if value, err := strconv.ParseBool(def); schema.Type == "boolean" && err == nil {
+			schema.Default = value
+		}
+	}
+
+	required := false
+	for _, r := range rules {
+		num := r.num
+		switch {
+		case r.name == "required":
+			required = true
+		case r.name == "oneof":
+			for _, option := range r.oneof {
+				value, err := strconv.ParseFloat(option, 64)
+				if numeric && err == nil {
+					schema.Enum = append(schema.Enum, value)
+					continue
+				}
+				schema.Enum = append(schema.Enum, option)
+			}
+		case schema.Type == "string":
+			schema.MinLength, schema.MaxLength = bound(r.name, &num, schema.MinLength, schema.MaxLength)
+		case schema.Type == "array":
+			schema.MinItems, schema.MaxItems = bound(r.name, &num, schema.MinItems, schema.MaxItems)
+		case numeric:
+			schema.Minimum, schema.Maximum = bound(r.name, &num, schema.Minimum, schema.Maximum)
+		}
+	}
+	return required
+}
+
+func bound(name string, num, min, max *float64) (*float64, *float64) {
+	if name == "min" {
+		return num, max
+	}
+	return min, num
+}
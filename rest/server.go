@@ -2,10 +2,16 @@ package rest
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"net"
 	"net/http"
+	"sort"
+	"strings"
 
 	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
 	"go.opentelemetry.io/otel/trace"
 
 	"github.com/DoomLordor/logger"
@@ -20,61 +26,181 @@ type Server struct {
 	config     Config
 	router     *mux.Router
 	httpServer *http.Server
+	listener   net.Listener
 	logger     *logger.Logger
+	routes     []RouteInfo
+	restUse    []mux.MiddlewareFunc
+	wsUse      []mux.MiddlewareFunc
+	authUse    []mux.MiddlewareFunc
+	spanHooks  []SpanHook
+	m          *Middlewares
+	openapi    *OpenAPI
+	codecs     *Codecs
+	errors     *ErrorMapper
 }
 
 func NewServer(config Config) *Server {
 	router := mux.NewRouter()
 	httpServer := &http.Server{
-		Addr:         config.BindAddress(),
-		WriteTimeout: config.WriteTimeout,
-		ReadTimeout:  config.ReadTimeout,
-		IdleTimeout:  config.IdleTimeout,
-		Handler:      router,
+		Addr:              config.BindAddress(),
+		WriteTimeout:      config.WriteTimeout,
+		ReadTimeout:       config.ReadTimeout,
+		ReadHeaderTimeout: config.ReadHeaderTimeout,
+		IdleTimeout:       config.IdleTimeout,
+		MaxHeaderBytes:    config.MaxHeaderBytes,
+		Handler:           router,
 	}
 	return &Server{
 		config:     config,
 		router:     router,
 		httpServer: httpServer,
 		logger:     logger.NewLogger("rest-server"),
+		codecs:     DefaultCodecs(),
+		errors:     NewErrorMapper(),
 	}
 }
 
-func (s *Server) Configuration(api []Api, authFunc AuthFunc, tracer trace.Tracer) error {
+func (s *Server) Use(middlewares ...mux.MiddlewareFunc) {
+	s.restUse = append(s.restUse, middlewares...)
+}
+
+func (s *Server) UseWs(middlewares ...mux.MiddlewareFunc) {
+	s.wsUse = append(s.wsUse, middlewares...)
+}
+
+// RegisterCodec adds a codec negotiated for REST requests, replacing the one registered for its content type
+func (s *Server) RegisterCodec(codec Codec, aliases ...string) {
+	s.codecs.Register(codec, aliases...)
+}
+
+// Errors returns the mapper converting errors returned by handlers, it is shared with gRPC by the API server
+func (s *Server) Errors() *ErrorMapper {
+	return s.errors
+}
+
+// UseAfterAuth adds middlewares wrapping every REST and WS handler after the token check
+func (s *Server) UseAfterAuth(middlewares ...mux.MiddlewareFunc) {
+	s.authUse = append(s.authUse, middlewares...)
+}
+
+// OnSpan adds hooks called once the server span of a REST request is started
+func (s *Server) OnSpan(hooks ...SpanHook) {
+	s.spanHooks = append(s.spanHooks, hooks...)
+}
+
+func (s *Server) Configuration(api []Api, authFunc AuthFunc, tracer trace.Tracer, registerer prometheus.Registerer) error {
 	s.logger.Info().Msg("Router configuration")
-	metrics, err := NewPrometheusService()
+	if format := s.config.Errors.Format; format != "" && format != ErrorFormatLegacy && format != ErrorFormatProblem {
+		return fmt.Errorf("%w: %q", InvalidErrorFormat, format)
+	}
+
+	timeoutStatus, err := s.config.timeoutStatus()
+	if err != nil {
+		return err
+	}
+
+	metrics, err := NewPrometheusService(registerer)
 	if err != nil {
 		return err
 	}
 
 	m := NewMiddlewares(authFunc, logger.NewLogger("middlewares-rest"), tracer)
-	s.router.Use(m.RecoveryMiddleware)
-	routerRest := s.router.PathPrefix("/api/v1").Subrouter()
-	routerRest.Use(m.CommonMiddleware)
-	routerRest.Use(m.TimeMiddleware)
+	m.codecs = s.codecs
+	m.errors = &errorRenderer{config: s.config.Errors, mapper: s.errors}
+	m.spanHooks = s.spanHooks
+	s.m = m
+	s.router.Use(m.RecoveryMiddleware, m.ErrorsMiddleware)
+	cors, err := newCors(s.config.Cors)
+	if err != nil {
+		return err
+	}
+
+	versions := make(map[string]*mux.Router)
+	routerVersion := func(version string) *mux.Router {
+		if router, ok := versions[version]; ok {
+			return router
+		}
+		router := s.router.PathPrefix(versionPrefix(s.config.Prefix, version)).Subrouter()
+		router.Use(versionMiddleware(version))
+		router.Use(cors.Middleware)
+		router.Use(m.CommonMiddleware)
+		router.Use(m.TimeMiddleware)
+		router.Use(m.CodecMiddleware)
+		router.Use(s.restUse...)
+		versions[version] = router
+		return router
+	}
+	routerVersion(s.config.version())
+
+	docs := newOpenAPIBuilder(s.config.OpenAPI, s.config.Errors)
 
 	routerWs := s.router.PathPrefix("/ws").Subrouter()
 	routerWs.Use(m.LoggingMiddleware)
+	routerWs.Use(s.wsUse...)
 
 	for _, a := range api {
+		var prefixCors map[string]*Cors
+		if corsApi, ok := a.(CorsApi); ok {
+			prefixCors = corsApi.CorsRest()
+		}
+		var prefixUse map[string]RouteMiddlewares
+		if middlewareApi, ok := a.(MiddlewareApi); ok {
+			prefixUse = middlewareApi.MiddlewaresRest()
+		}
+
 		routeMap := a.RegistrationRest()
-		for prefix, routes := range routeMap {
-			sub := routerRest.PathPrefix(prefix).Subrouter()
+		for _, version := range apiVersions(a, s.config.version()) {
+			routerRest := routerVersion(version)
+			for prefix, routes := range routeMap {
+				sub := routerRest.PathPrefix(prefix).Subrouter()
 
-			for _, route := range routes {
-				handlerFunc := m.TracingMiddleware(route.HandlerFunc)
-				handler := m.HandleWrapper(handlerFunc)
-				if route.Secure {
-					handler = m.TokenMiddleware(handler)
-				}
+				use := prefixUse[prefix]
+
+				for _, route := range routes {
+					maxBody, timeout := s.config.routeLimits(route)
+					handlerFunc := m.TimeoutMiddleware(m.TracingMiddleware(route.HandlerFunc), timeout, timeoutStatus)
+					handler := m.HandleWrapper(handlerFunc)
+					if timeout > 0 {
+						handler = withResponseController(handler)
+					}
+					handler = chain(handler, use.AfterAuth, route.Use.AfterAuth)
+					handler = s.afterAuth(handler)
+					if route.Secure {
+						handler = m.TokenMiddleware(handler)
+					}
+					handler = chain(handler, use.BeforeAuth, route.Use.BeforeAuth)
+					if maxBody > 0 {
+						handler = m.BodyLimit(maxBody, handler)
+					}
 
-				r := sub.Path(route.Pattern)
-				if route.Metrics {
+					r := sub.Path(route.Pattern)
 					path, _ := r.GetPathTemplate()
-					handler = metrics.RequestMetricsMiddleware(path, handler)
-				}
+					if route.Metrics {
+						handler = metrics.RequestMetricsMiddleware(path, handler)
+					}
+					if route.Deprecation != nil {
+						handler = metrics.DeprecationMiddleware(path, route.Deprecation, handler)
+					}
+					handler = chain(handler, use.BeforeMetrics, route.Use.BeforeMetrics)
 
-				r.Handler(handler).Methods(route.Methods...)
+					r.Handler(handler).Methods(route.Methods...)
+					err = cors.add(r, path, route.Methods, route.Cors, prefixCors[prefix])
+					if err != nil {
+						return err
+					}
+					docs.add(path, route)
+					s.routes = append(s.routes, RouteInfo{
+						Type:       RouteTypeRest,
+						Prefix:     prefix,
+						Pattern:    route.Pattern,
+						Path:       path,
+						Methods:    route.Methods,
+						Secure:     route.Secure,
+						Metrics:    route.Metrics,
+						Version:    version,
+						Deprecated: route.Deprecation != nil,
+					})
+				}
 			}
 		}
 
@@ -83,21 +209,67 @@ func (s *Server) Configuration(api []Api, authFunc AuthFunc, tracer trace.Tracer
 			sub := routerWs.PathPrefix(prefix).Subrouter()
 
 			for _, route := range routes {
-				handler := m.HandleWsWrapper(route.HandlerFunc)
+				handler := chain(m.HandleWsWrapper(route.HandlerFunc), route.Use.AfterAuth)
+				handler = s.afterAuth(handler)
 				if route.Secure {
 					handler = m.TokenMiddleware(handler)
 				}
-				sub.Handle(route.Pattern, handler).Methods(http.MethodGet)
+				handler = chain(handler, route.Use.BeforeMetrics, route.Use.BeforeAuth)
+				r := sub.Handle(route.Pattern, handler).Methods(http.MethodGet)
+				path, _ := r.GetPathTemplate()
+				s.routes = append(s.routes, RouteInfo{
+					Type:    RouteTypeWs,
+					Prefix:  prefix,
+					Pattern: route.Pattern,
+					Path:    path,
+					Methods: []string{http.MethodGet},
+					Secure:  route.Secure,
+				})
 			}
 		}
 	}
 
-	routerRest.Handle("", m.HandleWrapper(s.urls)).Methods(http.MethodGet)
+	if s.config.ListRoutes {
+		for _, routerRest := range versions {
+			r := routerRest.Handle("", m.HandleWrapper(s.urls)).Methods(http.MethodGet)
+			path, _ := r.GetPathTemplate()
+			_ = cors.add(r, path, []string{http.MethodGet})
+		}
+	}
+	cors.register(s.router)
+
+	s.openapi = docs.document()
+	if s.config.OpenAPI.Path != "" {
+		doc, err := json.Marshal(s.openapi)
+		if err != nil {
+			return err
+		}
+		s.router.Handle(s.config.OpenAPI.Path, openAPIHandler(doc)).Methods(http.MethodGet)
+		if ui := strings.TrimSuffix(s.config.OpenAPI.UIPath, "/"); ui != "" {
+			handler := openAPIUIHandler(ui, s.config.OpenAPI.Path)
+			s.router.Handle(ui, handler).Methods(http.MethodGet)
+			s.router.PathPrefix(ui + "/").Handler(handler).Methods(http.MethodGet)
+		}
+	}
 	s.router.NotFoundHandler = s.router.NewRoute().HandlerFunc(notFound).GetHandler()
 
 	return nil
 }
 
+func (s *Server) afterAuth(handler http.Handler) http.Handler {
+	return chain(handler, s.authUse)
+}
+
+// chain wraps handler so that the first middleware of the first list runs first
+func chain(handler http.Handler, lists ...[]mux.MiddlewareFunc) http.Handler {
+	for i := len(lists) - 1; i >= 0; i-- {
+		for j := len(lists[i]) - 1; j >= 0; j-- {
+			handler = lists[i][j](handler)
+		}
+	}
+	return handler
+}
+
 func (s *Server) urls(_ *http.Request) (any, int, error) {
 	res := make(map[string][]string, 10)
 
@@ -117,16 +289,41 @@ func (s *Server) urls(_ *http.Request) (any, int, error) {
 	return res, http.StatusOK, nil
 }
 
+func (s *Server) Routes() []RouteInfo {
+	res := make([]RouteInfo, len(s.routes))
+	copy(res, s.routes)
+	sort.SliceStable(res, func(i, j int) bool {
+		return res[i].Path < res[j].Path
+	})
+	return res
+}
+
 func (s *Server) Start() {
 	if !s.Active() {
 		return
 	}
+	if err := s.Listen(); err != nil {
+		s.logger.Fatal().Err(err).Send()
+	}
 	s.logger.Info().Msg("Server rest start")
-	if err := s.httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+	if err := s.httpServer.Serve(s.listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
 		s.logger.Fatal().Err(err).Send()
 	}
 }
 
+// Listen binds the server address, Start calls it when it was not called before
+func (s *Server) Listen() error {
+	if !s.Active() || s.listener != nil {
+		return nil
+	}
+	listener, err := net.Listen("tcp", s.httpServer.Addr)
+	if err != nil {
+		return err
+	}
+	s.listener = listener
+	return nil
+}
+
 func (s *Server) stop(ctx context.Context) error {
 	return s.httpServer.Shutdown(ctx)
 }
@@ -144,6 +341,19 @@ func (s *Server) Stop(ctx context.Context) error {
 	return err
 }
 
+// OpenAPI returns the document generated from the registered REST routes
+func (s *Server) OpenAPI() *OpenAPI {
+	return s.openapi
+}
+
+// RequestId returns the last request ID issued to a REST or WS request
+func (s *Server) RequestId() uint64 {
+	if s.m == nil {
+		return 0
+	}
+	return s.m.RequestId()
+}
+
 func (s *Server) Active() bool {
 	return s.config.Active
 }
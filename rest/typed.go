@@ -0,0 +1,198 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	SourcePath   = "path"
+	SourceQuery  = "query"
+	SourceHeader = "header"
+	SourceBody   = "body"
+)
+
+type FieldError struct {
+	Field   string `json:"field,omitempty" xml:"field,attr,omitempty"`
+	In      string `json:"in" xml:"in,attr"`
+	Message string `json:"message" xml:",chardata"`
+}
+
+// ValidationError is returned by typed handlers when binding or validation fails,
+// HandleWrapper renders it as 400 with the field errors
+type ValidationError struct {
+	Fields []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	parts := make([]string, 0, len(e.Fields))
+	for _, field := range e.Fields {
+		if field.Field == "" {
+			parts = append(parts, field.Message)
+			continue
+		}
+		parts = append(parts, field.Field+": "+field.Message)
+	}
+	return "validation failed: " + strings.Join(parts, "; ")
+}
+
+type ValidationErrorResponse struct {
+	Error  string       `json:"error" xml:"error"`
+	Fields []FieldError `json:"fields" xml:"fields>field"`
+}
+
+// Validator is implemented by request structs needing checks beyond the validate tag,
+// returning *ValidationError keeps field level details
+type Validator interface {
+	Validate() error
+}
+
+type TypedHandler[Req any, Resp any] func(ctx context.Context, req Req) (Resp, int, error)
+
+// Typed adapts handler to HandlerFuncRest binding Req fields from the request:
+//
+//	ID     string   `path:"id"`
+//	Limit  int      `query:"limit" default:"20" validate:"min=1,max=100"`
+//	Tags   []string `query:"tag"`
+//	Trace  string   `header:"X-Trace-Id"`
+//	Name   string   `json:"name" validate:"required"`
+//
+// Fields without path, query or header tags are decoded from the JSON body.
+// Typed panics when Req is not a struct or its tags are invalid.
+func Typed[Req any, Resp any](handler TypedHandler[Req, Resp]) HandlerFuncRest {
+	plan, err := planOf(reflect.TypeOf((*Req)(nil)).Elem())
+	if err != nil {
+		panic(fmt.Sprintf("rest.Typed: %s", err))
+	}
+
+	return func(r *http.Request) (any, int, error) {
+		var req Req
+		err := bind(r, plan, reflect.ValueOf(&req).Elem())
+		if err != nil {
+			return nil, http.StatusBadRequest, err
+		}
+
+		if validator, ok := any(&req).(Validator); ok {
+			err = validator.Validate()
+			if err != nil {
+				return nil, http.StatusBadRequest, asValidationError(err)
+			}
+		}
+
+		resp, code, err := handler(r.Context(), req)
+		if err != nil {
+			return nil, code, err
+		}
+		return resp, code, nil
+	}
+}
+
+// TypedRoute returns route serving handler through Typed with the Docs request and response types filled in
+func TypedRoute[Req any, Resp any](route RouteRest, handler TypedHandler[Req, Resp]) *RouteRest {
+	docs := Docs{}
+	if route.Docs != nil {
+		docs = *route.Docs
+	}
+	if docs.Request == nil {
+		docs.Request = *new(Req)
+	}
+	if docs.Response == nil {
+		docs.Response = *new(Resp)
+	}
+	docs.typed = true
+
+	route.Docs = &docs
+	route.HandlerFunc = Typed(handler)
+	return &route
+}
+
+func bind(r *http.Request, plan *structPlan, value reflect.Value) error {
+	fields := make([]FieldError, 0)
+
+	var body *presence
+	if plan.body && r.Body != nil && r.Body != http.NoBody {
+		data, codec, err := decode(r, value.Addr().Interface())
+		var restErr *Error
+		if errors.As(err, &restErr) {
+			return err
+		}
+		if err != nil && !errors.Is(err, io.EOF) {
+			fields = append(fields, bodyError(err))
+			return &ValidationError{Fields: fields}
+		}
+		plan.clearUnbound(value)
+		if err == nil && codec.ContentType() == jsonContentType {
+			body = parsePresence(data)
+		}
+	}
+
+	var query map[string][]string
+	// bound holds fields read from the path, query or headers, false when parsing failed
+	bound := make(map[*fieldPlan]bool)
+	for _, field := range plan.fields {
+		var values []string
+		switch field.source {
+		case SourcePath:
+			if pathValue, ok := mux.Vars(r)[field.key]; ok {
+				values = []string{pathValue}
+			}
+		case SourceQuery:
+			if query == nil {
+				query = r.URL.Query()
+			}
+			values = query[field.key]
+		case SourceHeader:
+			values = r.Header.Values(field.key)
+		default:
+			continue
+		}
+
+		if len(values) == 0 && field.def != "" {
+			values = []string{field.def}
+		}
+		if len(values) == 0 {
+			continue
+		}
+
+		fieldValue, _ := fieldOf(value, field.index, true)
+		err := setValues(fieldValue, values)
+		if err != nil {
+			fields = append(fields, FieldError{Field: field.name, In: field.source, Message: err.Error()})
+		}
+		bound[field] = err == nil
+	}
+
+	fields = plan.validate(value, "", bound, body, fields)
+	if len(fields) != 0 {
+		return &ValidationError{Fields: fields}
+	}
+	return nil
+}
+
+func bodyError(err error) FieldError {
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		return FieldError{Field: typeErr.Field, In: SourceBody, Message: "must be " + typeName(typeErr.Type)}
+	}
+	var syntaxErr *json.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		return FieldError{In: SourceBody, Message: "invalid JSON"}
+	}
+	return FieldError{In: SourceBody, Message: err.Error()}
+}
+
+func asValidationError(err error) *ValidationError {
+	var validationErr *ValidationError
+	if errors.As(err, &validationErr) {
+		return validationErr
+	}
+	return &ValidationError{Fields: []FieldError{{In: SourceBody, Message: err.Error()}}}
+}
@@ -0,0 +1,138 @@
+package rest
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+type Paging struct {
+	Limit int    `query:"limit" default:"20"`
+	Trace string `header:"X-Trace-Id"`
+}
+
+type Scope struct {
+	Owner string `path:"owner"`
+}
+
+type embeddedValueRequest struct {
+	Scope
+	Paging
+	Name string `json:"name"`
+}
+
+type embeddedPointerRequest struct {
+	*Scope
+	*Paging
+	Name string `json:"name"`
+}
+
+func typedRequest(method, target, body string, vars map[string]string, header http.Header) *http.Request {
+	var r *http.Request
+	if body == "" {
+		r = httptest.NewRequest(method, target, nil)
+	} else {
+		r = httptest.NewRequest(method, target, strings.NewReader(body))
+		r.Header.Set("Content-Type", jsonContentType)
+	}
+	for key, values := range header {
+		r.Header[key] = values
+	}
+	return mux.SetURLVars(r, vars)
+}
+
+// callTyped serves r with a typed handler and returns the bound request
+func callTyped[Req any](t *testing.T, r *http.Request) (Req, error) {
+	t.Helper()
+	var got Req
+	handler := Typed(func(_ context.Context, req Req) (struct{}, int, error) {
+		got = req
+		return struct{}{}, http.StatusOK, nil
+	})
+	_, _, err := handler(r)
+	return got, err
+}
+
+func TestTypedEmbedded(t *testing.T) {
+	tests := []struct {
+		name   string
+		target string
+		vars   map[string]string
+		header http.Header
+		body   string
+		owner  string
+		limit  int
+		trace  string
+	}{
+		{
+			name:   "all sources",
+			target: "/items?limit=5",
+			vars:   map[string]string{"owner": "bob"},
+			header: http.Header{"X-Trace-Id": {"abc"}},
+			body:   `{"name":"box"}`,
+			owner:  "bob",
+			limit:  5,
+			trace:  "abc",
+		},
+		{
+			name:   "default without body",
+			target: "/items",
+			vars:   map[string]string{"owner": "bob"},
+			owner:  "bob",
+			limit:  20,
+		},
+		{
+			name:   "body can not set bound fields",
+			target: "/items",
+			body:   `{"name":"box","Owner":"eve","Limit":99,"Trace":"x"}`,
+			limit:  20,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name+" value", func(t *testing.T) {
+			r := typedRequest(http.MethodPost, tt.target, tt.body, tt.vars, tt.header)
+			got, err := callTyped[embeddedValueRequest](t, r)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Owner != tt.owner || got.Limit != tt.limit || got.Trace != tt.trace {
+				t.Errorf("got %+v", got)
+			}
+		})
+		t.Run(tt.name+" pointer", func(t *testing.T) {
+			r := typedRequest(http.MethodPost, tt.target, tt.body, tt.vars, tt.header)
+			got, err := callTyped[embeddedPointerRequest](t, r)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var owner string
+			if got.Scope != nil {
+				owner = got.Scope.Owner
+			}
+			if owner != tt.owner || got.Paging == nil || got.Limit != tt.limit || got.Trace != tt.trace {
+				t.Errorf("got owner %q paging %+v", owner, got.Paging)
+			}
+		})
+	}
+}
+
+func TestPlanEmbedded(t *testing.T) {
+	plan, err := planOf(reflect.TypeOf(embeddedPointerRequest{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sources := make(map[string]string)
+	for _, field := range plan.fields {
+		sources[field.name] = field.source
+	}
+	want := map[string]string{"owner": SourcePath, "limit": SourceQuery, "X-Trace-Id": SourceHeader, "name": SourceBody}
+	if !reflect.DeepEqual(sources, want) {
+		t.Errorf("got fields %v, want %v", sources, want)
+	}
+}
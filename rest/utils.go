@@ -3,6 +3,8 @@ package rest
 import (
 	"io"
 	"net/http"
+
+	"github.com/gorilla/mux"
 )
 
 type LoggingResponseWriter struct {
@@ -23,8 +25,25 @@ func (lrw *LoggingResponseWriter) Code() int {
 	return lrw.code
 }
 
+// Unwrap lets http.ResponseController reach the underlying writer, e.g. to flush or hijack
+func (lrw *LoggingResponseWriter) Unwrap() http.ResponseWriter {
+	return lrw.ResponseWriter
+}
+
 func notFound(w http.ResponseWriter, _ *http.Request) {
 	w.Header().Add("Content-Type", "application/json")
 	w.WriteHeader(http.StatusNotFound)
 	_, _ = io.WriteString(w, `{"error": "url not found"}`)
 }
+
+func PathTemplate(r *http.Request) string {
+	route := mux.CurrentRoute(r)
+	if route == nil {
+		return r.URL.Path
+	}
+	template, err := route.GetPathTemplate()
+	if err != nil {
+		return r.URL.Path
+	}
+	return template
+}
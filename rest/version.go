@@ -0,0 +1,77 @@
+package rest
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+const defaultPrefix = "/api"
+
+// VersionedApi is implemented by an Api served under several versions, others use the default version
+type VersionedApi interface {
+	Versions() []string
+}
+
+// Deprecation marks a route deprecated, it is announced in response headers and counted in metrics
+type Deprecation struct {
+	// Date is when the route was deprecated, Deprecation is sent as true when zero
+	Date time.Time
+	// Sunset is when the route is going to be removed
+	Sunset time.Time
+	// Link points to the replacement of the route
+	Link string
+}
+
+func (d *Deprecation) setHeaders(header http.Header) {
+	if d.Date.IsZero() {
+		header.Set("Deprecation", "true")
+	} else {
+		header.Set("Deprecation", "@"+strconv.FormatInt(d.Date.Unix(), 10))
+	}
+	if !d.Sunset.IsZero() {
+		header.Set("Sunset", d.Sunset.UTC().Format(http.TimeFormat))
+	}
+	if d.Link != "" {
+		header.Add("Link", "<"+d.Link+`>; rel="successor-version"`)
+	}
+}
+
+type versionKey struct{}
+
+// Version returns the API version of the REST route serving r
+func Version(r *http.Request) string {
+	version, _ := r.Context().Value(versionKey{}).(string)
+	return version
+}
+
+func versionMiddleware(version string) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		f := func(w http.ResponseWriter, r *http.Request) {
+			ctx := context.WithValue(r.Context(), versionKey{}, version)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		}
+		return http.HandlerFunc(f)
+	}
+}
+
+func apiVersions(a Api, defaultVersion string) []string {
+	if versioned, ok := a.(VersionedApi); ok {
+		if versions := versioned.Versions(); len(versions) != 0 {
+			return versions
+		}
+	}
+	return []string{defaultVersion}
+}
+
+// versionPrefix joins the configured prefix with version, an empty prefix keeps /api and / serves from the root
+func versionPrefix(prefix, version string) string {
+	if prefix == "" {
+		prefix = defaultPrefix
+	}
+	return strings.TrimSuffix(prefix, "/") + "/" + strings.Trim(version, "/")
+}
@@ -0,0 +1,21 @@
+package systemd
+
+import (
+	"time"
+)
+
+type Config struct {
+	Active bool   `env:"SYSTEMD_NOTIFY" envDefault:"true"`
+	Socket string `env:"NOTIFY_SOCKET"`
+	// WatchdogUsec and WatchdogPid are set by systemd when WatchdogSec is configured for the unit
+	WatchdogUsec uint64 `env:"WATCHDOG_USEC"`
+	WatchdogPid  int    `env:"WATCHDOG_PID"`
+}
+
+// WatchdogInterval is half of the systemd watchdog timeout, zero when the watchdog is off
+func (c *Config) WatchdogInterval() time.Duration {
+	if c.WatchdogUsec == 0 {
+		return 0
+	}
+	return time.Duration(c.WatchdogUsec) * time.Microsecond / 2
+}
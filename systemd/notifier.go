@@ -0,0 +1,170 @@
+package systemd
+
+import (
+	"errors"
+	"net"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/DoomLordor/logger"
+)
+
+const (
+	StateReady    = "READY=1"
+	StateStopping = "STOPPING=1"
+	StateWatchdog = "WATCHDOG=1"
+	statusPrefix  = "STATUS="
+)
+
+var NotifyDisabled = errors.New("systemd notify disabled")
+
+type HealthCheck func() error
+
+// Notifier implements the sd_notify protocol over the NOTIFY_SOCKET unixgram socket
+type Notifier struct {
+	config Config
+	logger *logger.Logger
+	mu     *sync.Mutex
+	checks map[string]HealthCheck
+	stop   chan struct{}
+	done   chan struct{}
+}
+
+func NewNotifier(config Config) *Notifier {
+	return &Notifier{
+		config: config,
+		logger: logger.NewLogger("systemd"),
+		mu:     &sync.Mutex{},
+		checks: make(map[string]HealthCheck),
+	}
+}
+
+func (n *Notifier) Active() bool {
+	return n.config.Active && n.config.Socket != ""
+}
+
+// Notify sends states in one datagram, each state is a KEY=VALUE line
+func (n *Notifier) Notify(states ...string) error {
+	if !n.Active() {
+		return NotifyDisabled
+	}
+
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: n.config.Socket, Net: "unixgram"})
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	_, err = conn.Write([]byte(strings.Join(states, "\n")))
+	return err
+}
+
+func (n *Notifier) Status(status string) {
+	n.send(statusPrefix + status)
+}
+
+func (n *Notifier) Ready(status string) {
+	n.send(StateReady, statusPrefix+status)
+}
+
+func (n *Notifier) Stopping(status string) {
+	n.send(StateStopping, statusPrefix+status)
+}
+
+// AddHealthCheck gates watchdog pings, systemd restarts the service when a check keeps failing
+func (n *Notifier) AddHealthCheck(name string, check HealthCheck) {
+	n.mu.Lock()
+	n.checks[name] = check
+	n.mu.Unlock()
+}
+
+// Start runs watchdog pings when systemd expects them for this process
+func (n *Notifier) Start() {
+	interval := n.config.WatchdogInterval()
+	if !n.Active() || interval == 0 {
+		return
+	}
+	if n.config.WatchdogPid != 0 && n.config.WatchdogPid != os.Getpid() {
+		return
+	}
+
+	n.stop = make(chan struct{})
+	n.done = make(chan struct{})
+	go n.run(interval)
+	n.logger.Info().Str("interval", interval.String()).Msg("Systemd watchdog start")
+}
+
+func (n *Notifier) Stop() {
+	if n.stop == nil {
+		return
+	}
+	close(n.stop)
+	<-n.done
+	n.stop = nil
+}
+
+func (n *Notifier) run(interval time.Duration) {
+	defer close(n.done)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	healthy := true
+	for {
+		select {
+		case <-n.stop:
+			return
+		case <-ticker.C:
+		}
+
+		name, err := n.check()
+		if err != nil {
+			if healthy {
+				n.logger.Warn().Str("check", name).Err(err).Msg("Systemd watchdog ping skipped")
+				n.Status("health check " + name + " failing: " + err.Error())
+			}
+			healthy = false
+			continue
+		}
+
+		if !healthy {
+			n.logger.Info().Msg("Systemd watchdog ping resumed")
+			n.send(StateWatchdog, statusPrefix+"serving")
+			healthy = true
+			continue
+		}
+		n.send(StateWatchdog)
+	}
+}
+
+func (n *Notifier) check() (string, error) {
+	n.mu.Lock()
+	names := make([]string, 0, len(n.checks))
+	checks := make(map[string]HealthCheck, len(n.checks))
+	for name, check := range n.checks {
+		names = append(names, name)
+		checks[name] = check
+	}
+	n.mu.Unlock()
+
+	sort.Strings(names)
+	for _, name := range names {
+		if err := checks[name](); err != nil {
+			return name, err
+		}
+	}
+	return "", nil
+}
+
+func (n *Notifier) send(states ...string) {
+	if !n.Active() {
+		return
+	}
+	err := n.Notify(states...)
+	if err != nil {
+		n.logger.Err(err).Strs("states", states).Msg("Systemd notify failed")
+	}
+}
@@ -0,0 +1,102 @@
+package systemd
+
+import (
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func listen(t *testing.T) (*net.UnixConn, string) {
+	t.Helper()
+	dir, err := os.MkdirTemp("", "notify")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = os.RemoveAll(dir) })
+
+	socket := filepath.Join(dir, "notify.sock")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	return conn, socket
+}
+
+func receive(t *testing.T, conn *net.UnixConn) string {
+	t.Helper()
+	buf := make([]byte, 1024)
+	_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	n, err := conn.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(buf[:n])
+}
+
+func TestNotifierStates(t *testing.T) {
+	conn, socket := listen(t)
+	n := NewNotifier(Config{Active: true, Socket: socket})
+
+	n.Ready("serving")
+	if got := receive(t, conn); got != "READY=1\nSTATUS=serving" {
+		t.Errorf("got %q after Ready", got)
+	}
+	n.Status("configuring")
+	if got := receive(t, conn); got != "STATUS=configuring" {
+		t.Errorf("got %q after Status", got)
+	}
+	n.Stopping("stopping")
+	if got := receive(t, conn); got != "STOPPING=1\nSTATUS=stopping" {
+		t.Errorf("got %q after Stopping", got)
+	}
+}
+
+func TestNotifierWatchdog(t *testing.T) {
+	conn, socket := listen(t)
+	n := NewNotifier(Config{Active: true, Socket: socket, WatchdogUsec: 20000, WatchdogPid: os.Getpid()})
+
+	failing := make(chan error, 1)
+	n.AddHealthCheck("servers", func() error {
+		select {
+		case err := <-failing:
+			return err
+		default:
+			return nil
+		}
+	})
+	n.Start()
+	defer n.Stop()
+
+	if got := receive(t, conn); got != StateWatchdog {
+		t.Errorf("got %q, want a watchdog ping", got)
+	}
+
+	failing <- errors.New("rest exited")
+	got := receive(t, conn)
+	for got == StateWatchdog {
+		got = receive(t, conn)
+	}
+	if got != "STATUS=health check servers failing: rest exited" {
+		t.Errorf("got %q, want the failing check reported instead of a ping", got)
+	}
+	if got := receive(t, conn); got != "WATCHDOG=1\nSTATUS=serving" {
+		t.Errorf("got %q, want the ping resumed after the check recovers", got)
+	}
+}
+
+func TestNotifierInactive(t *testing.T) {
+	n := NewNotifier(Config{Active: true})
+	if err := n.Notify(StateReady); !errors.Is(err, NotifyDisabled) {
+		t.Errorf("got %v without a socket, want NotifyDisabled", err)
+	}
+
+	_, socket := listen(t)
+	n = NewNotifier(Config{Socket: socket})
+	if err := n.Notify(StateReady); !errors.Is(err, NotifyDisabled) {
+		t.Errorf("got %v when disabled, want NotifyDisabled", err)
+	}
+}
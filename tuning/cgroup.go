@@ -0,0 +1,153 @@
+package tuning
+
+import (
+	"bufio"
+	"errors"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// v1 reports "unlimited" memory as a huge page-aligned number
+const unlimitedV1 = math.MaxInt64 / 2
+
+var CgroupNotFound = errors.New("cgroup not found")
+
+type Limits struct {
+	Version int `json:"version"`
+	// CPU is the quota in cores, 0 when not limited
+	CPU float64 `json:"cpu"`
+	// Memory is the limit in bytes, 0 when not limited
+	Memory int64 `json:"memory"`
+}
+
+// ReadLimits reads the limits of the current process cgroup below root,
+// procCgroup is the path of the /proc/self/cgroup file
+func ReadLimits(root, procCgroup string) (Limits, error) {
+	paths, err := readProcCgroup(procCgroup)
+	if err != nil {
+		return Limits{}, err
+	}
+
+	if _, err = os.Stat(filepath.Join(root, "cgroup.controllers")); err == nil {
+		return readV2(root, paths[""])
+	}
+	return readV1(root, paths)
+}
+
+// readProcCgroup maps controllers to cgroup paths, the v2 unified hierarchy has the empty controller
+func readProcCgroup(path string) (map[string]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	res := make(map[string]string, 10)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		parts := strings.SplitN(scanner.Text(), ":", 3)
+		if len(parts) != 3 {
+			continue
+		}
+		if parts[1] == "" {
+			res[""] = parts[2]
+			continue
+		}
+		for _, controller := range strings.Split(parts[1], ",") {
+			res[controller] = parts[2]
+		}
+	}
+	return res, scanner.Err()
+}
+
+func readV2(root, path string) (Limits, error) {
+	limits := Limits{Version: 2}
+
+	data, err := readCgroupFile(root, path, "cpu.max")
+	if err != nil && !errors.Is(err, CgroupNotFound) {
+		return limits, err
+	}
+	if fields := strings.Fields(data); len(fields) == 2 && fields[0] != "max" {
+		quota, errQuota := strconv.ParseFloat(fields[0], 64)
+		period, errPeriod := strconv.ParseFloat(fields[1], 64)
+		if errQuota == nil && errPeriod == nil && period > 0 {
+			limits.CPU = quota / period
+		}
+	}
+
+	data, err = readCgroupFile(root, path, "memory.max")
+	if err != nil && !errors.Is(err, CgroupNotFound) {
+		return limits, err
+	}
+	if data != "" && data != "max" {
+		limits.Memory, err = strconv.ParseInt(data, 10, 64)
+		if err != nil {
+			return limits, err
+		}
+	}
+
+	return limits, nil
+}
+
+func readV1(root string, paths map[string]string) (Limits, error) {
+	limits := Limits{Version: 1}
+
+	cpuRoot := filepath.Join(root, "cpu")
+	if _, err := os.Stat(cpuRoot); err != nil {
+		cpuRoot = filepath.Join(root, "cpu,cpuacct")
+	}
+	quota, err := readCgroupFile(cpuRoot, paths["cpu"], "cpu.cfs_quota_us")
+	if err != nil && !errors.Is(err, CgroupNotFound) {
+		return limits, err
+	}
+	period, err := readCgroupFile(cpuRoot, paths["cpu"], "cpu.cfs_period_us")
+	if err != nil && !errors.Is(err, CgroupNotFound) {
+		return limits, err
+	}
+	if quota != "" && period != "" {
+		q, errQuota := strconv.ParseFloat(quota, 64)
+		p, errPeriod := strconv.ParseFloat(period, 64)
+		if errQuota == nil && errPeriod == nil && q > 0 && p > 0 {
+			limits.CPU = q / p
+		}
+	}
+
+	memory, err := readCgroupFile(filepath.Join(root, "memory"), paths["memory"], "memory.limit_in_bytes")
+	if err != nil && !errors.Is(err, CgroupNotFound) {
+		return limits, err
+	}
+	if memory != "" {
+		limit, errParse := strconv.ParseInt(memory, 10, 64)
+		if errParse != nil {
+			return limits, errParse
+		}
+		if limit < unlimitedV1 {
+			limits.Memory = limit
+		}
+	}
+
+	if limits.CPU == 0 && limits.Memory == 0 && quota == "" && memory == "" {
+		return limits, CgroupNotFound
+	}
+	return limits, nil
+}
+
+// readCgroupFile looks for the file in the process cgroup first, then in the hierarchy root
+// which is what a container with its own cgroup namespace sees
+func readCgroupFile(root, path, name string) (string, error) {
+	candidates := []string{filepath.Join(root, path, name), filepath.Join(root, name)}
+	for _, candidate := range candidates {
+		data, err := os.ReadFile(candidate)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimSpace(string(data)), nil
+	}
+	return "", CgroupNotFound
+}
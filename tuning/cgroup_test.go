@@ -0,0 +1,150 @@
+package tuning
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReadLimits(t *testing.T) {
+	tests := []struct {
+		name       string
+		procCgroup string
+		files      map[string]string
+		want       Limits
+		wantErr    error
+		anyErr     bool
+	}{
+		{
+			name:       "v2 limited",
+			procCgroup: "0::/app\n",
+			files: map[string]string{
+				"cgroup.controllers": "cpu memory",
+				"app/cpu.max":        "50000 100000",
+				"app/memory.max":     "536870912",
+			},
+			want: Limits{Version: 2, CPU: 0.5, Memory: 536870912},
+		},
+		{
+			name:       "v2 unlimited",
+			procCgroup: "0::/app\n",
+			files: map[string]string{
+				"cgroup.controllers": "cpu memory",
+				"app/cpu.max":        "max 100000",
+				"app/memory.max":     "max",
+			},
+			want: Limits{Version: 2},
+		},
+		{
+			name:       "v2 cgroup namespace root",
+			procCgroup: "0::/\n",
+			files: map[string]string{
+				"cgroup.controllers": "cpu memory",
+				"cpu.max":            "200000 100000",
+				"memory.max":         "1073741824",
+			},
+			want: Limits{Version: 2, CPU: 2, Memory: 1073741824},
+		},
+		{
+			name:       "v2 invalid memory",
+			procCgroup: "0::/app\n",
+			files: map[string]string{
+				"cgroup.controllers": "cpu memory",
+				"app/memory.max":     "lots",
+			},
+			anyErr: true,
+		},
+		{
+			name:       "v1 limited",
+			procCgroup: "5:memory:/docker/x\n4:cpu,cpuacct:/docker/x\n1:name=systemd:/docker/x\n",
+			files: map[string]string{
+				"cpu,cpuacct/docker/x/cpu.cfs_quota_us":  "150000",
+				"cpu,cpuacct/docker/x/cpu.cfs_period_us": "100000",
+				"memory/docker/x/memory.limit_in_bytes":  "1073741824",
+			},
+			want: Limits{Version: 1, CPU: 1.5, Memory: 1073741824},
+		},
+		{
+			name:       "v1 unlimited",
+			procCgroup: "5:memory:/docker/x\n4:cpu:/docker/x\n",
+			files: map[string]string{
+				"cpu/docker/x/cpu.cfs_quota_us":         "-1",
+				"cpu/docker/x/cpu.cfs_period_us":        "100000",
+				"memory/docker/x/memory.limit_in_bytes": "9223372036854771712",
+			},
+			want: Limits{Version: 1},
+		},
+		{
+			name:       "v1 missing",
+			procCgroup: "5:memory:/docker/x\n4:cpu:/docker/x\n",
+			want:       Limits{Version: 1},
+			wantErr:    CgroupNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			root := filepath.Join(dir, "cgroup")
+			procCgroup := filepath.Join(dir, "proc-cgroup")
+			write(t, procCgroup, tt.procCgroup)
+			for name, content := range tt.files {
+				write(t, filepath.Join(root, name), content)
+			}
+
+			limits, err := ReadLimits(root, procCgroup)
+			switch {
+			case tt.anyErr:
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			case tt.wantErr != nil:
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("got error %v, want %v", err, tt.wantErr)
+				}
+			case err != nil:
+				t.Fatal(err)
+			}
+			if limits != tt.want {
+				t.Errorf("got %+v, want %+v", limits, tt.want)
+			}
+		})
+	}
+}
+
+func TestProcsFor(t *testing.T) {
+	tests := []struct {
+		cpu      float64
+		numCPU   int
+		minProcs int
+		want     int
+	}{
+		{cpu: 0.5, numCPU: 8, minProcs: 0, want: 1},
+		{cpu: 0.5, numCPU: 8, minProcs: 1, want: 1},
+		{cpu: 1.5, numCPU: 8, minProcs: 1, want: 1},
+		{cpu: 2, numCPU: 8, minProcs: 1, want: 2},
+		{cpu: 2.9, numCPU: 8, minProcs: 1, want: 2},
+		{cpu: 16, numCPU: 4, minProcs: 1, want: 4},
+		{cpu: 1, numCPU: 8, minProcs: 2, want: 2},
+	}
+
+	for _, tt := range tests {
+		if got := procsFor(tt.cpu, tt.numCPU, tt.minProcs); got != tt.want {
+			t.Errorf("procsFor(%v, %d, %d) = %d, want %d", tt.cpu, tt.numCPU, tt.minProcs, got, tt.want)
+		}
+	}
+}
+
+func write(t *testing.T, path, content string) {
+	t.Helper()
+	err := os.MkdirAll(filepath.Dir(path), 0o755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.WriteFile(path, []byte(content), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
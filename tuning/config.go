@@ -0,0 +1,9 @@
+package tuning
+
+type Config struct {
+	Active         bool    `env:"RUNTIME_TUNING" envDefault:"false"`
+	CgroupRoot     string  `env:"RUNTIME_TUNING_CGROUP_ROOT" envDefault:"/sys/fs/cgroup"`
+	ProcCgroup     string  `env:"RUNTIME_TUNING_PROC_CGROUP" envDefault:"/proc/self/cgroup"`
+	MemoryHeadroom float64 `env:"RUNTIME_TUNING_MEMORY_HEADROOM" envDefault:"0.1"`
+	MinProcs       int     `env:"RUNTIME_TUNING_MIN_PROCS" envDefault:"1"`
+}
@@ -0,0 +1,183 @@
+package tuning
+
+import (
+	"os"
+	"runtime"
+	"runtime/debug"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/DoomLordor/logger"
+
+	"github.com/DoomLordor/go-apiserver/metrics"
+)
+
+type Decision struct {
+	Active             bool     `json:"active"`
+	Limits             Limits   `json:"limits"`
+	PreviousGOMAXPROCS int      `json:"previous_gomaxprocs"`
+	GOMAXPROCS         int      `json:"gomaxprocs"`
+	MemoryLimit        int64    `json:"memory_limit"`
+	Notes              []string `json:"notes,omitempty"`
+	Error              string   `json:"error,omitempty"`
+}
+
+type Tuner struct {
+	config   Config
+	logger   *logger.Logger
+	mu       *sync.RWMutex
+	decision Decision
+
+	cpuQuota    prometheus.Gauge
+	memoryMax   prometheus.Gauge
+	gomaxprocs  prometheus.Gauge
+	memoryLimit prometheus.Gauge
+}
+
+func NewTuner(config Config, registerer prometheus.Registerer) (*Tuner, error) {
+	cpuQuota := prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "runtime_tuning_cgroup_cpu_quota",
+		Help: "CPU quota of the container in cores, 0 when unlimited",
+	})
+	memoryMax := prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "runtime_tuning_cgroup_memory_limit_bytes",
+		Help: "Memory limit of the container in bytes, 0 when unlimited",
+	})
+	gomaxprocs := prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "runtime_tuning_gomaxprocs",
+		Help: "GOMAXPROCS after runtime tuning",
+	})
+	memoryLimit := prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "runtime_tuning_memory_limit_bytes",
+		Help: "Go runtime soft memory limit after runtime tuning",
+	})
+
+	cpuQuota, err := metrics.Register(registerer, cpuQuota)
+	if err != nil {
+		return nil, err
+	}
+
+	memoryMax, err = metrics.Register(registerer, memoryMax)
+	if err != nil {
+		return nil, err
+	}
+
+	gomaxprocs, err = metrics.Register(registerer, gomaxprocs)
+	if err != nil {
+		return nil, err
+	}
+
+	memoryLimit, err = metrics.Register(registerer, memoryLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Tuner{
+		config:      config,
+		logger:      logger.NewLogger("runtime-tuning"),
+		mu:          &sync.RWMutex{},
+		cpuQuota:    cpuQuota,
+		memoryMax:   memoryMax,
+		gomaxprocs:  gomaxprocs,
+		memoryLimit: memoryLimit,
+	}, nil
+}
+
+func (t *Tuner) Active() bool {
+	return t.config.Active
+}
+
+// Apply reads the container limits and adjusts GOMAXPROCS and the soft memory limit,
+// explicit GOMAXPROCS and GOMEMLIMIT environment variables take precedence
+func (t *Tuner) Apply() Decision {
+	decision := Decision{
+		Active:             t.Active(),
+		PreviousGOMAXPROCS: runtime.GOMAXPROCS(0),
+		GOMAXPROCS:         runtime.GOMAXPROCS(0),
+		MemoryLimit:        debug.SetMemoryLimit(-1),
+	}
+	defer t.store(decision)
+
+	if !t.Active() {
+		return decision
+	}
+
+	limits, err := ReadLimits(t.config.CgroupRoot, t.config.ProcCgroup)
+	decision.Limits = limits
+	if err != nil {
+		decision.Error = err.Error()
+		t.logger.Warn().Str("warning", err.Error()).Msg("Runtime tuning skipped")
+		return decision
+	}
+
+	switch {
+	case os.Getenv("GOMAXPROCS") != "":
+		decision.Notes = append(decision.Notes, "GOMAXPROCS is set explicitly")
+	case limits.CPU == 0:
+		decision.Notes = append(decision.Notes, "CPU is not limited")
+	default:
+		procs := procsFor(limits.CPU, runtime.NumCPU(), t.config.MinProcs)
+		runtime.GOMAXPROCS(procs)
+		decision.GOMAXPROCS = procs
+	}
+
+	switch {
+	case os.Getenv("GOMEMLIMIT") != "":
+		decision.Notes = append(decision.Notes, "GOMEMLIMIT is set explicitly")
+	case limits.Memory == 0:
+		decision.Notes = append(decision.Notes, "memory is not limited")
+	default:
+		headroom := t.config.MemoryHeadroom
+		if headroom < 0 || headroom >= 1 {
+			headroom = 0
+		}
+		limit := int64(float64(limits.Memory) * (1 - headroom))
+		debug.SetMemoryLimit(limit)
+		decision.MemoryLimit = limit
+	}
+
+	t.logger.Info().
+		Int("cgroup_version", limits.Version).
+		Float64("cpu_quota", limits.CPU).
+		Int64("memory_max", limits.Memory).
+		Int("previous_gomaxprocs", decision.PreviousGOMAXPROCS).
+		Int("gomaxprocs", decision.GOMAXPROCS).
+		Int64("memory_limit", decision.MemoryLimit).
+		Strs("notes", decision.Notes).
+		Msg("Runtime tuned")
+
+	return decision
+}
+
+// procsFor floors the CPU quota so that the process is not throttled, keeping at least minProcs and one
+func procsFor(cpu float64, numCPU, minProcs int) int {
+	procs := int(cpu)
+	if procs > numCPU {
+		procs = numCPU
+	}
+	if procs < minProcs {
+		procs = minProcs
+	}
+	if procs < 1 {
+		procs = 1
+	}
+	return procs
+}
+
+func (t *Tuner) Decision() any {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.decision
+}
+
+func (t *Tuner) store(decision Decision) {
+	t.cpuQuota.Set(decision.Limits.CPU)
+	t.memoryMax.Set(float64(decision.Limits.Memory))
+	t.gomaxprocs.Set(float64(decision.GOMAXPROCS))
+	t.memoryLimit.Set(float64(decision.MemoryLimit))
+
+	t.mu.Lock()
+	t.decision = decision
+	t.mu.Unlock()
+}
@@ -0,0 +1,175 @@
+package apiserver
+
+import (
+	"expvar"
+	"fmt"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/DoomLordor/go-apiserver/inflight"
+	"github.com/DoomLordor/go-apiserver/metrics"
+)
+
+const (
+	PhaseNew         = "new"
+	PhaseConfiguring = "configuring"
+	PhaseConfigured  = "configured"
+	PhaseStarting    = "starting"
+	PhaseServing     = "serving"
+	PhaseStopping    = "stopping"
+	PhaseStopped     = "stopped"
+)
+
+var phases = []string{PhaseNew, PhaseConfiguring, PhaseConfigured, PhaseStarting, PhaseServing, PhaseStopping, PhaseStopped}
+
+// varsName is the expvar map served at /debug/vars, it holds a map per server name
+const varsName = "apiserver"
+
+var (
+	varsMu     = &sync.Mutex{}
+	varsOwners = make(map[string]*APIServer)
+)
+
+type serverVar struct {
+	name    string
+	help    string
+	counter bool
+	value   func() float64
+}
+
+func (s *APIServer) Phase() string {
+	phase, _ := s.phase.Load().(string)
+	return phase
+}
+
+func (s *APIServer) setPhase(phase string) {
+	s.phase.Store(phase)
+	switch phase {
+	case PhaseServing:
+		s.systemd.Ready(phase)
+	case PhaseStopping:
+		s.systemd.Stopping(phase)
+	default:
+		s.systemd.Status(phase)
+	}
+}
+
+// registerVars publishes server internals as Prometheus metrics and expvar,
+// both read the same sources so the two views agree
+func (s *APIServer) registerVars() error {
+	vars := []serverVar{
+		{
+			name: "rest_inflight_requests",
+			help: "REST requests being served",
+			value: func() float64 {
+				return float64(s.inflight.CountBy(inflight.TypeRest, ""))
+			},
+		},
+		{
+			name: "ws_connections",
+			help: "Open WebSocket connections",
+			value: func() float64 {
+				return float64(s.inflight.CountBy(inflight.TypeWs, ""))
+			},
+		},
+		{
+			name: "grpc_active_streams",
+			help: "Active gRPC streams",
+			value: func() float64 {
+				return float64(s.inflight.CountBy(inflight.TypeGrpc, inflight.MethodStream))
+			},
+		},
+		{
+			name:    "request_ids_total",
+			help:    "Request IDs issued to REST and WS requests",
+			counter: true,
+			value: func() float64 {
+				return float64(s.httpServer.RequestId())
+			},
+		},
+	}
+
+	values := s.publishVars()
+
+	for _, v := range vars {
+		v := v
+		var collector prometheus.Collector
+		if v.counter {
+			collector = prometheus.NewCounterFunc(prometheus.CounterOpts{Name: v.name, Help: v.help}, v.value)
+		} else {
+			collector = prometheus.NewGaugeFunc(prometheus.GaugeOpts{Name: v.name, Help: v.help}, v.value)
+		}
+		_, err := metrics.Register(s.metrics.Registerer(), collector)
+		if err != nil {
+			return err
+		}
+		values.Set(v.name, expvar.Func(func() any {
+			return v.value()
+		}))
+	}
+
+	for _, phase := range phases {
+		phase := phase
+		gauge := prometheus.NewGaugeFunc(
+			prometheus.GaugeOpts{
+				Name:        "server_phase",
+				Help:        "Current server phase, 1 for the current one",
+				ConstLabels: prometheus.Labels{"phase": phase},
+			},
+			func() float64 {
+				if s.Phase() == phase {
+					return 1
+				}
+				return 0
+			},
+		)
+		_, err := metrics.Register(s.metrics.Registerer(), gauge)
+		if err != nil {
+			return err
+		}
+	}
+	values.Set("server_phase", expvar.Func(func() any {
+		return s.Phase()
+	}))
+	return nil
+}
+
+// publishVars returns the expvar map of the server, a name already used by a running server gets a numeric suffix
+func (s *APIServer) publishVars() *expvar.Map {
+	varsMu.Lock()
+	defer varsMu.Unlock()
+
+	if s.varsKey == "" {
+		name := s.config.name()
+		key := name
+		for i := 2; varsOwners[key] != nil; i++ {
+			key = fmt.Sprintf("%s-%d", name, i)
+		}
+		varsOwners[key] = s
+		s.varsKey = key
+	}
+
+	servers, ok := expvar.Get(varsName).(*expvar.Map)
+	if !ok {
+		servers = expvar.NewMap(varsName)
+	}
+	values := &expvar.Map{}
+	servers.Set(s.varsKey, values)
+	return values
+}
+
+// unpublishVars releases the name of a stopped server
+func (s *APIServer) unpublishVars() {
+	varsMu.Lock()
+	defer varsMu.Unlock()
+
+	if s.varsKey == "" {
+		return
+	}
+	if servers, ok := expvar.Get(varsName).(*expvar.Map); ok {
+		servers.Delete(s.varsKey)
+	}
+	delete(varsOwners, s.varsKey)
+	s.varsKey = ""
+}
@@ -0,0 +1,13 @@
+package watchdog
+
+import (
+	"time"
+)
+
+type Config struct {
+	Active         bool          `env:"WATCHDOG" envDefault:"false"`
+	Interval       time.Duration `env:"WATCHDOG_INTERVAL" envDefault:"30s"`
+	StuckThreshold time.Duration `env:"WATCHDOG_STUCK_THRESHOLD" envDefault:"1m"`
+	GrowthSamples  int           `env:"WATCHDOG_GROWTH_SAMPLES" envDefault:"10"`
+	History        int           `env:"WATCHDOG_HISTORY" envDefault:"60"`
+}
@@ -0,0 +1,36 @@
+package watchdog
+
+import (
+	"net/http"
+
+	"github.com/DoomLordor/go-apiserver/debug"
+)
+
+func (w *Watchdog) RegistrationDebug() debug.RouteMap {
+	return debug.RouteMap{
+		"/watchdog": {
+			{
+				Methods:     []string{http.MethodGet},
+				Pattern:     "",
+				HandlerFunc: w.status,
+			},
+			{
+				Methods:     []string{http.MethodGet},
+				Pattern:     "/goroutines",
+				HandlerFunc: w.goroutineGroups,
+			},
+		},
+	}
+}
+
+func (w *Watchdog) status(_ *http.Request) (any, int, error) {
+	return w.Status(), http.StatusOK, nil
+}
+
+func (w *Watchdog) goroutineGroups(_ *http.Request) (any, int, error) {
+	groups, err := GroupStacks()
+	if err != nil {
+		return nil, http.StatusInternalServerError, err
+	}
+	return groups, http.StatusOK, nil
+}
@@ -0,0 +1,84 @@
+package watchdog
+
+import (
+	"bufio"
+	"bytes"
+	"regexp"
+	"runtime/pprof"
+	"sort"
+	"strings"
+)
+
+var (
+	goroutineHeader = regexp.MustCompile(`^goroutine \d+ \[([^\],]+)`)
+	frameArgs       = regexp.MustCompile(`\([^()]*\)$`)
+	createdIn       = regexp.MustCompile(` in goroutine \d+$`)
+)
+
+type StackGroup struct {
+	Count  int            `json:"count"`
+	States map[string]int `json:"states"`
+	Stack  []string       `json:"stack"`
+}
+
+// GroupStacks dumps all goroutines and groups them by stack signature
+func GroupStacks() ([]StackGroup, error) {
+	buf := &bytes.Buffer{}
+	err := pprof.Lookup("goroutine").WriteTo(buf, 2)
+	if err != nil {
+		return nil, err
+	}
+
+	groups := make(map[string]*StackGroup, 100)
+	state := ""
+	stack := make([]string, 0, 32)
+
+	flush := func() {
+		if state == "" {
+			return
+		}
+		signature := strings.Join(stack, "\n")
+		group, ok := groups[signature]
+		if !ok {
+			group = &StackGroup{
+				States: make(map[string]int, 1),
+				Stack:  append([]string{}, stack...),
+			}
+			groups[signature] = group
+		}
+		group.Count++
+		group.States[state]++
+		state = ""
+		stack = stack[:0]
+	}
+
+	scanner := bufio.NewScanner(buf)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if match := goroutineHeader.FindStringSubmatch(line); match != nil {
+			flush()
+			state = match[1]
+			continue
+		}
+		// frame lines hold the function, the following indented lines hold file:line
+		if line == "" || strings.HasPrefix(line, "\t") {
+			continue
+		}
+		line = createdIn.ReplaceAllString(line, "")
+		stack = append(stack, frameArgs.ReplaceAllString(line, ""))
+	}
+	flush()
+
+	res := make([]StackGroup, 0, len(groups))
+	for _, group := range groups {
+		res = append(res, *group)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Count != res[j].Count {
+			return res[i].Count > res[j].Count
+		}
+		return strings.Join(res[i].Stack, "") < strings.Join(res[j].Stack, "")
+	})
+	return res, scanner.Err()
+}
@@ -0,0 +1,276 @@
+package watchdog
+
+import (
+	"errors"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/DoomLordor/logger"
+
+	"github.com/DoomLordor/go-apiserver/inflight"
+	"github.com/DoomLordor/go-apiserver/metrics"
+)
+
+var InvalidInterval = errors.New("watchdog interval must be positive")
+
+type Sample struct {
+	Time       time.Time `json:"time"`
+	Goroutines int       `json:"goroutines"`
+}
+
+type Status struct {
+	Active     bool             `json:"active"`
+	Goroutines int              `json:"goroutines"`
+	Growing    bool             `json:"growing"`
+	Samples    []Sample         `json:"samples"`
+	Stuck      []inflight.Entry `json:"stuck"`
+}
+
+type Watchdog struct {
+	config   Config
+	logger   *logger.Logger
+	tracker  *inflight.Tracker
+	mu       *sync.RWMutex
+	samples  []Sample
+	rising   int
+	growing  bool
+	stuck    []inflight.Entry
+	reported map[uint64]struct{}
+	done     chan struct{}
+	wg       *sync.WaitGroup
+
+	goroutines    prometheus.Gauge
+	growth        prometheus.Gauge
+	stuckRequests *prometheus.GaugeVec
+	stuckTotal    *prometheus.CounterVec
+}
+
+func NewWatchdog(config Config, tracker *inflight.Tracker, registerer prometheus.Registerer) (*Watchdog, error) {
+	if config.Active && config.Interval <= 0 {
+		return nil, InvalidInterval
+	}
+
+	goroutines := prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "watchdog_goroutines",
+			Help: "Number of goroutines at the last watchdog sample",
+		},
+	)
+
+	growth := prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "watchdog_goroutine_growth",
+			Help: "Whether the goroutine count grows monotonically",
+		},
+	)
+
+	stuckRequests := prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "watchdog_stuck_requests",
+			Help: "Handlers running longer than the stuck threshold",
+		},
+		[]string{"type"},
+	)
+
+	stuckTotal := prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "watchdog_stuck_requests_total",
+			Help: "Total number of handlers detected running longer than the stuck threshold",
+		},
+		[]string{"type"},
+	)
+
+	goroutines, err := metrics.Register(registerer, goroutines)
+	if err != nil {
+		return nil, err
+	}
+
+	growth, err = metrics.Register(registerer, growth)
+	if err != nil {
+		return nil, err
+	}
+
+	stuckRequests, err = metrics.Register(registerer, stuckRequests)
+	if err != nil {
+		return nil, err
+	}
+
+	stuckTotal, err = metrics.Register(registerer, stuckTotal)
+	if err != nil {
+		return nil, err
+	}
+
+	if config.History < config.GrowthSamples+1 {
+		config.History = config.GrowthSamples + 1
+	}
+
+	return &Watchdog{
+		config:        config,
+		logger:        logger.NewLogger("watchdog"),
+		tracker:       tracker,
+		mu:            &sync.RWMutex{},
+		samples:       make([]Sample, 0, config.History),
+		reported:      make(map[uint64]struct{}, 10),
+		wg:            &sync.WaitGroup{},
+		goroutines:    goroutines,
+		growth:        growth,
+		stuckRequests: stuckRequests,
+		stuckTotal:    stuckTotal,
+	}, nil
+}
+
+func (w *Watchdog) Start() {
+	if !w.Active() {
+		return
+	}
+
+	w.done = make(chan struct{})
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		ticker := time.NewTicker(w.config.Interval)
+		defer ticker.Stop()
+
+		w.check()
+		for {
+			select {
+			case <-w.done:
+				return
+			case <-ticker.C:
+				w.check()
+			}
+		}
+	}()
+	w.logger.Info().Msg("Watchdog start")
+}
+
+func (w *Watchdog) Stop() {
+	if w.done == nil {
+		return
+	}
+	close(w.done)
+	w.wg.Wait()
+	w.done = nil
+}
+
+func (w *Watchdog) Active() bool {
+	return w.config.Active
+}
+
+func (w *Watchdog) Status() Status {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	samples := make([]Sample, len(w.samples))
+	copy(samples, w.samples)
+	stuck := make([]inflight.Entry, len(w.stuck))
+	copy(stuck, w.stuck)
+	return Status{
+		Active:     w.Active(),
+		Goroutines: runtime.NumGoroutine(),
+		Growing:    w.growing,
+		Samples:    samples,
+		Stuck:      stuck,
+	}
+}
+
+func (w *Watchdog) check() {
+	w.sampleGoroutines()
+	w.checkStuck()
+}
+
+func (w *Watchdog) sampleGoroutines() {
+	sample := Sample{Time: time.Now(), Goroutines: runtime.NumGoroutine()}
+	w.goroutines.Set(float64(sample.Goroutines))
+
+	w.mu.Lock()
+	if n := len(w.samples); n > 0 && sample.Goroutines > w.samples[n-1].Goroutines {
+		w.rising++
+	} else {
+		w.rising = 0
+	}
+	if len(w.samples) == cap(w.samples) {
+		w.samples = append(w.samples[:0], w.samples[1:]...)
+	}
+	w.samples = append(w.samples, sample)
+
+	growing := w.config.GrowthSamples > 0 && w.rising >= w.config.GrowthSamples
+	changed := growing != w.growing
+	w.growing = growing
+	w.mu.Unlock()
+
+	if growing {
+		w.growth.Set(1)
+	} else {
+		w.growth.Set(0)
+	}
+
+	if !changed {
+		return
+	}
+	if !growing {
+		w.logger.Info().Int("goroutines", sample.Goroutines).Msg("Goroutine growth stopped")
+		return
+	}
+
+	event := w.logger.Warn().Int("goroutines", sample.Goroutines).Int("samples", w.config.GrowthSamples)
+	groups, err := GroupStacks()
+	if err == nil && len(groups) > 0 {
+		event = event.Int("top_count", groups[0].Count).Strs("top_stack", groups[0].Stack)
+	}
+	event.Msg("Goroutine count grows monotonically")
+}
+
+func (w *Watchdog) checkStuck() {
+	if w.tracker == nil || w.config.StuckThreshold <= 0 {
+		return
+	}
+
+	now := time.Now()
+	stuck := make([]inflight.Entry, 0, 10)
+	counts := map[string]int{
+		inflight.TypeRest: 0,
+		inflight.TypeWs:   0,
+		inflight.TypeGrpc: 0,
+	}
+	alive := make(map[uint64]struct{}, 10)
+
+	for _, entry := range w.tracker.List() {
+		if now.Sub(entry.StartedAt) < w.config.StuckThreshold {
+			// list is sorted by age, the rest are younger
+			break
+		}
+		stuck = append(stuck, entry)
+		counts[entry.Type]++
+		alive[entry.ID] = struct{}{}
+		if _, ok := w.reported[entry.ID]; ok {
+			continue
+		}
+		w.reported[entry.ID] = struct{}{}
+		w.stuckTotal.WithLabelValues(entry.Type).Inc()
+		w.logger.Warn().
+			Uint64("id", entry.ID).
+			Str("type", entry.Type).
+			Str("method", entry.Method).
+			Str("path", entry.Path).
+			Uint64("requestId", entry.RequestId).
+			Str("age", entry.Age).
+			Msg("Stuck request")
+	}
+
+	for id := range w.reported {
+		if _, ok := alive[id]; !ok {
+			delete(w.reported, id)
+		}
+	}
+	for entryType, count := range counts {
+		w.stuckRequests.WithLabelValues(entryType).Set(float64(count))
+	}
+
+	w.mu.Lock()
+	w.stuck = stuck
+	w.mu.Unlock()
+}